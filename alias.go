@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// LoadAliases loads user aliases in default CLI
+func LoadAliases(r io.Reader) error {
+	return defaultCLI.LoadAliases(r)
+}
+
+// LoadAliases loads user aliases from the [alias] section of an ini style
+// config, one `name = expansion` per line. Lines starting with # or ; are
+// comments
+func (cli *CLI) LoadAliases(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	section := ""
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if section != "alias" {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i == -1 {
+			return fmt.Errorf("line %d: invalid alias: %s", n, line)
+		}
+		name, exp := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("line %d: invalid alias name: %s", n, name)
+		}
+		cli.options.aliases[name] = exp
+	}
+	return sc.Err()
+}
+
+// UserAliases returns the names of the user aliases of the command not
+// shadowed by subcommands, sorted. Only root commands have user aliases
+func (c *command) UserAliases() (out []string) {
+	if c.parent != nil {
+		return nil
+	}
+	for name := range c.opts.aliases {
+		if _, ok := c.subcmdsMap[name]; !ok {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return
+}
+
+func (c *command) UserAliasDescription() (out []string) {
+	for _, name := range c.UserAliases() {
+		out = append(out, c.cmdColumns(name, "= "+c.opts.aliases[name]))
+	}
+	return
+}
+
+// expandAlias replaces the arg at i by the expansion of the user alias it
+// names, if it is in the position of a subcommand of the root command.
+// Subcommands take precedence over aliases
+func (p *parser) expandAlias(i int) error {
+	c := p.currentCmd()
+	if c.parent != nil || p.tokenType(p.args[i]) != tokCMD {
+		return nil
+	}
+	for {
+		name := p.args[i]
+		if _, ok := c.LookupSubcommand(name); ok {
+			return nil
+		}
+		exp, ok := p.cli.options.aliases[name]
+		if !ok {
+			return nil
+		}
+		if p.expanded[name] {
+			return usageErrorf("recursive alias: %s", name)
+		}
+		words, err := shellquote.Split(exp)
+		if err != nil {
+			return usageErrorf("invalid alias %s: %v", name, err)
+		}
+		if len(words) == 0 {
+			return usageErrorf("empty alias: %s", name)
+		}
+		if p.expanded == nil {
+			p.expanded = map[string]bool{}
+		}
+		p.expanded[name] = true
+		args := append([]string{}, p.args[:i]...)
+		args = append(args, words...)
+		p.args = append(args, p.args[i+1:]...)
+	}
+}
@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"encoding"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -45,16 +48,34 @@ func (fs *flagSet) Get(n string) *argument {
 	return fs.short[n]
 }
 
+// Longs returns the long names of all the flags
+func (fs *flagSet) Longs() (out []string) {
+	for _, a := range fs.all {
+		out = append(out, a.long)
+	}
+	return
+}
+
 func (fs *flagSet) All() []*argument {
 	return fs.all
 }
 
+// Match returns the flags with a long name starting with prefix
+func (fs *flagSet) Match(prefix string) (out []*argument) {
+	for _, f := range fs.all {
+		if strings.HasPrefix(f.long, prefix) {
+			out = append(out, f)
+		}
+	}
+	return
+}
+
 func (fs *flagSet) Autocomplete(val string) []string {
 	flags := []string{}
+	for _, f := range fs.Match(val) {
+		flags = append(flags, f.long)
+	}
 	for _, f := range fs.all {
-		if strings.HasPrefix(f.long, val) {
-			flags = append(flags, f.long)
-		}
 		if strings.HasPrefix(f.short, val) {
 			flags = append(flags, f.short)
 		}
@@ -64,24 +85,50 @@ func (fs *flagSet) Autocomplete(val string) []string {
 }
 
 type argument struct {
-	path        *path
-	typ         reflect.Type
-	def         []string
-	long        string
-	short       string
-	env         string
-	help        string
-	placeholder string
-	global      bool
-	positional  bool
-	required    bool
-	enum        *enum
-	isSlice     bool
-	isSet       bool
-	completers  []Completer
-	opts        *cliOptions
+	path          *path
+	typ           reflect.Type
+	def           []string
+	long          string
+	short         string
+	env           []envVar
+	help          string
+	placeholder   string
+	global        bool
+	positional    bool
+	required      bool
+	secret        bool
+	prompt        string
+	enum          *enum
+	isSlice       bool
+	isSet         bool
+	source        valueSource
+	sourceEnv     string
+	completers    []Completer
+	opts          *cliOptions
+	min           *float64
+	max           *float64
+	minLen        int
+	maxLen        int
+	pattern       *regexp.Regexp
+	oneOf         []string
+	groups        []*flagGroup
+	requiredIfTag []string
+	excludesTag   []string
+	requiredIf    []*condition
+	excludes      []*condition
 }
 
+// valueSource is where the value of an argument came from
+type valueSource uint8
+
+const (
+	sourceNone valueSource = iota
+	sourceCommandLine
+	sourceEnv
+	sourceDefault
+	sourcePrompt
+)
+
 func (a *argument) IsBool() bool {
 	return a.typ.Kind() == reflect.Bool
 }
@@ -92,36 +139,126 @@ func (a *argument) IsSet() bool {
 
 func (a *argument) Reset() {
 	a.isSet = false
+	a.source = sourceNone
+	a.sourceEnv = ""
+}
+
+// Source describes where the value came from
+func (a *argument) Source() string {
+	switch a.source {
+	case sourceCommandLine:
+		return "command line"
+	case sourceEnv:
+		return "env " + a.sourceEnv
+	case sourceDefault:
+		return "default"
+	case sourcePrompt:
+		return "prompt"
+	}
+	return ""
+}
+
+func (a *argument) setSource(src valueSource) {
+	if a.source == sourceNone {
+		a.source = src
+	}
 }
 
 func (a *argument) SetValue(val string) error {
 	a.isSet = true
+	a.setSource(sourceCommandLine)
+	if tum, ok := a.path.valueDeref().Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return a.invalidValue(val, tum.UnmarshalText([]byte(val)))
+	}
 	if a.enum != nil {
-		return a.path.Set(a.enum.Value(val))
+		v := a.enum.Value(val)
+		if v == nil {
+			return a.invalidValue(val, fmt.Errorf("must be one of: %s", strings.Join(a.enum.Names(), ", ")))
+		}
+		return a.path.Set(v)
 	}
-	return a.path.SetScalar(val)
+	return a.invalidValue(val, a.path.SetScalar(val))
 }
 
 func (a *argument) Append(s string) error {
 	if a.isSlice {
 		a.isSet = true
-		return a.path.AppendToSlice(s)
+		a.setSource(sourceCommandLine)
+		return a.invalidValue(s, a.path.AppendToSlice(s))
 	}
 	return fmt.Errorf("not an array or a slice")
 }
 
-func (a *argument) SetEnv() error {
+// invalidValue wraps a non nil err in ErrInvalidValue. The values of
+// secrets are left out
+func (a *argument) invalidValue(val string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if a.secret {
+		return ErrInvalidValue{
+			Flag:   a.Name(),
+			Source: a.Source(),
+			Cause:  redactedError{err: err, val: val},
+			Secret: true,
+		}
+	}
+	return ErrInvalidValue{
+		Flag:   a.Name(),
+		Value:  val,
+		Source: a.Source(),
+		Cause:  err,
+	}
+}
+
+// envVar is an environment variable name an argument can be read from
+type envVar struct {
+	name       string
+	deprecated bool
+}
+
+// EnvNames returns the env var names that are not deprecated
+func (a *argument) EnvNames() (out []string) {
+	for _, e := range a.env {
+		if !e.deprecated {
+			out = append(out, e.name)
+		}
+	}
+	return
+}
+
+func (a *argument) lookupEnv() (string, envVar, bool) {
+	for _, e := range a.env {
+		if val, ok := os.LookupEnv(e.name); ok {
+			return val, e, true
+		}
+	}
+	return "", envVar{}, false
+}
+
+// SetEnv sets the value from the first env var found. A warning is written
+// to w if the value comes from a deprecated name
+func (a *argument) SetEnv(w io.Writer) error {
 	if a.isSet {
 		return nil
 	}
-	val, ok := os.LookupEnv(a.env)
+	val, e, ok := a.lookupEnv()
 	if !ok {
 		return nil
 	}
+	if e.deprecated {
+		fmt.Fprintf(w, "warning: environment variable %s is deprecated", e.name)
+		if names := a.EnvNames(); len(names) > 0 {
+			fmt.Fprintf(w, ", use %s instead", names[0])
+		}
+		fmt.Fprintln(w)
+	}
+	a.setSource(sourceEnv)
+	a.sourceEnv = e.name
 	if a.isSlice {
 		words, err := shellquote.Split(val)
 		if err != nil {
-			return err
+			return a.invalidValue(val, err)
 		}
 		for _, s := range words {
 			if err := a.Append(s); err != nil {
@@ -137,6 +274,7 @@ func (a *argument) SetDefaultValue() error {
 	if a.def == nil {
 		return nil
 	}
+	a.setSource(sourceDefault)
 	if a.isSlice {
 		for _, s := range a.def {
 			if err := a.Append(s); err != nil {
@@ -148,10 +286,51 @@ func (a *argument) SetDefaultValue() error {
 	return a.SetValue(a.def[0])
 }
 
+// Resolve sets the value from env, prompt or default if not set from the
+// command line and checks if a required argument has a value from any of the
+// sources. Only required arguments are prompted for, pr is nil when prompting
+// is disabled
+func (a *argument) Resolve(w io.Writer, pr *prompter) error {
+	if err := a.SetEnv(w); err != nil {
+		return err
+	}
+	if !a.IsSet() && a.required && pr != nil {
+		if err := pr.Prompt(a); err != nil {
+			return err
+		}
+	}
+	if !a.IsSet() {
+		if err := a.SetDefaultValue(); err != nil {
+			panic("failed to set default value for: " + a.Name())
+		}
+	}
+	if a.required && !a.IsSet() {
+		return ErrRequired{
+			Name:       a.Name(),
+			Env:        a.EnvNames(),
+			Positional: a.positional,
+		}
+	}
+	return nil
+}
+
+// Name returns the long flag or the placeholder for positionals
+func (a *argument) Name() string {
+	if a.positional {
+		return a.placeholder
+	}
+	return a.long
+}
+
 func (a *argument) Complete(val string) (out []string) {
 	if a.enum != nil {
 		return a.enum.Complete(val)
 	}
+	for _, v := range a.oneOf {
+		if strings.HasPrefix(v, val) {
+			out = append(out, v+" ")
+		}
+	}
 	for _, f := range a.completers {
 		out = append(out, f.Complete(val)...)
 	}
@@ -166,8 +345,12 @@ func (a *argument) Usage() string {
 		}
 		return fmt.Sprintf("[%s]", a.placeholder)
 	}
+	return a.usage(!a.required)
+}
+
+func (a *argument) usage(optional bool) string {
 	b := strings.Builder{}
-	if !a.required {
+	if optional {
 		b.WriteByte('[')
 	}
 	if a.short != "" {
@@ -179,7 +362,7 @@ func (a *argument) Usage() string {
 		b.WriteByte(byte(a.opts.separator))
 		b.WriteString(a.placeholder)
 	}
-	if !a.required {
+	if optional {
 		b.WriteByte(']')
 	}
 	return b.String()
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+type batchKey struct{}
+
+var errNestedScript = errors.New("script: already running a script")
+
+// ErrLine is the error of a line of a script
+type ErrLine struct {
+	Line int
+	Err  error
+
+	cli *CLI
+}
+
+func (e ErrLine) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e ErrLine) Unwrap() error {
+	return e.Err
+}
+
+func (e ErrLine) ExitCode() int {
+	if e.cli == nil {
+		return ExitCode(e.Err)
+	}
+	return e.cli.ExitCode(e.Err)
+}
+
+// ErrLines holds the errors of the failed lines of a script run with
+// keepGoing, each an ErrLine
+type ErrLines []error
+
+func (e ErrLines) Error() string {
+	return fmt.Sprintf("script: %d lines failed", len(e))
+}
+
+func (e ErrLines) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target
+func (e ErrLines) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target
+func (e ErrLines) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch is a ready made set of flags running command lines from a file or
+// stdin. Embed it in the root command and call Run from the Run of the root
+// command when Enabled
+type Batch struct {
+	File      string `short:"f" env:"-" usage:"run the command lines of file, - for stdin"`
+	Batch     bool   `env:"-" usage:"run the command lines read from stdin"`
+	KeepGoing bool   `long:"keep-going" env:"-" usage:"run the next lines when a line fails"`
+}
+
+// Enabled reports whether command lines are to be run
+func (b *Batch) Enabled() bool {
+	return b.File != "" || b.Batch
+}
+
+// Run runs the command lines of the file or stdin. Lines keep running after
+// a failure if KeepGoing is set or the strategy is OnErrorContinue
+func (b *Batch) Run(ctx context.Context) error {
+	cli := cliFromContext(ctx)
+	if cli == nil || len(cli.cmdList) == 0 {
+		return errors.New("batch: not executed by a CLI")
+	}
+	if !b.Enabled() {
+		return nil
+	}
+	if ctx.Value(batchKey{}) != nil {
+		return errNestedScript
+	}
+	keepGoing := b.KeepGoing || cli.options.strategy == OnErrorContinue
+	r := cli.stdin
+	if b.File != "" && b.File != "-" {
+		f, err := os.Open(b.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return cli.RunScript(ctx, r, keepGoing)
+}
+
+// RunScript runs the lines of r as the args of the root command of the last
+// Parse. Lines starting with # are comments, a line ending with \ continues
+// on the next and $VAR or ${VAR} are replaced by environment variables
+// outside single quotes once the line is split in words.
+// Errors are returned as ErrLine. With keepGoing the errors are written,
+// running continues and the errors are returned as ErrLines
+func (cli *CLI) RunScript(ctx context.Context, r io.Reader, keepGoing bool) error {
+	if len(cli.cmdList) == 0 {
+		return errors.New("script: no command parsed")
+	}
+	if ctx.Value(batchKey{}) != nil {
+		return errNestedScript
+	}
+	ctx = context.WithValue(ctx, batchKey{}, true)
+	root := cli.cmdList[0].Name
+
+	defer cli.saveState()()
+
+	errs := []error{}
+	// run runs the line starting at line number start
+	run := func(line string, start int) error {
+		if line == "" || strings.HasPrefix(line, "#") {
+			return nil
+		}
+		err := cli.runScriptLine(ctx, root, line)
+		if err == nil {
+			return nil
+		}
+		err = ErrLine{Line: start, Err: err, cli: cli}
+		if !keepGoing || ctx.Err() != nil {
+			return err
+		}
+		fmt.Fprintln(cli.errorOut, "error:", err)
+		errs = append(errs, err)
+		return nil
+	}
+
+	sc := bufio.NewScanner(r)
+	line, start := "", 0
+	for n := 1; sc.Scan() && ctx.Err() == nil; n++ {
+		if line == "" {
+			start = n
+		}
+		text := strings.TrimSpace(sc.Text())
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		if err := run(strings.TrimSpace(line+text), start); err != nil {
+			return err
+		}
+		line = ""
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := run(strings.TrimSpace(line), start); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return Exit(ErrLines(errs), cli.ExitCode(cli.selectError(errs)))
+	}
+	return nil
+}
+
+func (cli *CLI) runScriptLine(ctx context.Context, root, line string) error {
+	words, err := splitScriptLine(line)
+	if err != nil {
+		return err
+	}
+	return cli.runLine(ctx, append([]string{root}, words...))
+}
+
+// splitScriptLine splits line in words like shellquote.Split and expands the
+// environment variables of each word, except in single quotes or escaped by
+// a backslash. Values are never split nor unquoted
+func splitScriptLine(line string) ([]string, error) {
+	words := []string{}
+	word, raw := strings.Builder{}, strings.Builder{}
+	inWord := false
+	// flush writes the text pending expansion to the word
+	flush := func() {
+		word.WriteString(os.ExpandEnv(raw.String()))
+		raw.Reset()
+	}
+	rs := []rune(line)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; r {
+		case ' ', '\t', '\n':
+			if inWord {
+				flush()
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case '\\':
+			if i++; i == len(rs) {
+				return nil, shellquote.UnterminatedEscapeError
+			}
+			flush()
+			word.WriteRune(rs[i])
+		case '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != '\'' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, shellquote.UnterminatedSingleQuoteError
+			}
+			flush()
+			word.WriteString(string(rs[i+1 : j]))
+			i = j
+		case '"':
+			flush()
+			for i++; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) && strings.ContainsRune("$`\"\\", rs[i+1]) {
+					i++
+					flush()
+					word.WriteRune(rs[i])
+					continue
+				}
+				raw.WriteRune(rs[i])
+			}
+			if i == len(rs) {
+				return nil, shellquote.UnterminatedDoubleQuoteError
+			}
+			flush()
+		default:
+			raw.WriteRune(r)
+		}
+		inWord = true
+	}
+	if inWord {
+		flush()
+		words = append(words, word.String())
+	}
+	return words, nil
+}
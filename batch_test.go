@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type batchTestRoot struct {
+	Batch
+	Label string
+	Add   *batchTestAdd
+	Echo  *batchTestEcho
+	Fail  *batchTestFail
+}
+
+func (c *batchTestRoot) Run(ctx context.Context) error {
+	if c.Enabled() {
+		return c.Batch.Run(ctx)
+	}
+	return errors.New("no command")
+}
+
+var batchTestLabels []string
+
+func (c *batchTestRoot) PersistentPostRun(ctx context.Context) error {
+	batchTestLabels = append(batchTestLabels, c.Label)
+	return nil
+}
+
+var batchTestSums []int
+
+type batchTestAdd struct {
+	Nums  []int `cli:"positional"`
+	Twice bool
+}
+
+func (c *batchTestAdd) Run(ctx context.Context) error {
+	sum := 0
+	for _, n := range c.Nums {
+		sum += n
+	}
+	if c.Twice {
+		sum *= 2
+	}
+	batchTestSums = append(batchTestSums, sum)
+	return nil
+}
+
+var batchTestWords [][]string
+
+type batchTestEcho struct {
+	A string `cli:"positional"`
+	B string `cli:"positional"`
+	C string `cli:"positional"`
+	D string `cli:"positional"`
+}
+
+func (c *batchTestEcho) Run(ctx context.Context) error {
+	batchTestWords = append(batchTestWords, []string{c.A, c.B, c.C, c.D})
+	return nil
+}
+
+type batchTestFail struct{}
+
+func (c *batchTestFail) Run(ctx context.Context) error {
+	return Exit(errors.New("failed"), 4)
+}
+
+func TestBatch(t *testing.T) {
+	t.Setenv("BATCH_TEST_NUM", "5")
+	t.Setenv("BATCH_TEST_SPACE", "a b")
+	t.Setenv("BATCH_TEST_QUOTE", "it's")
+	t.Setenv("BATCH_TEST_FLAG", "x --twice")
+	script := strings.Join([]string{
+		"# comment",
+		"add 1 --twice",
+		"",
+		"add \\",
+		"  $BATCH_TEST_NUM",
+		"fail",
+		"add --nope",
+		"-f other.txt",
+		"--label line add 7",
+		`echo '$BATCH_TEST_NUM' "$BATCH_TEST_NUM" \$BATCH_TEST_NUM "\$BATCH_TEST_NUM"`,
+		`echo $BATCH_TEST_SPACE "$BATCH_TEST_QUOTE" $BATCH_TEST_FLAG`,
+		`echo "$BATCH_TEST_NUM"x '$BATCH_TEST_NUM'x`,
+	}, "\n")
+	file := filepath.Join(t.TempDir(), "script.txt")
+	if err := os.WriteFile(file, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var root *batchTestRoot
+	run := func(options []Option, args ...string) (string, error) {
+		batchTestSums, batchTestWords, batchTestLabels = nil, nil, nil
+		errOut := &bytes.Buffer{}
+		p := NewCLI(options...)
+		root = &batchTestRoot{}
+		p.NewCommand("root", root)
+		p.errorOut = errOut
+		p.stdin = strings.NewReader(script)
+		if err := p.Parse(append([]string{"root"}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		err := p.Run(context.Background())
+		return errOut.String(), err
+	}
+
+	_, err := run(nil, "-f", file)
+	lineErr := ErrLine{}
+	if !errors.As(err, &lineErr) || lineErr.Line != 6 || ExitCode(err) != 4 {
+		t.Fatal("expected error of line 6, got:", err)
+	}
+	if len(batchTestSums) != 2 || batchTestSums[0] != 2 || batchTestSums[1] != 5 {
+		t.Fatal("wrong lines executed:", batchTestSums)
+	}
+
+	errOut, err := run(nil, "--label", "outer", "--batch", "--keep-going")
+	if err == nil || err.Error() != "script: 3 lines failed" || ExitCode(err) != ExitError {
+		t.Fatal("expected failed lines, got:", err)
+	}
+	lines := ErrLines{}
+	if !errors.As(err, &lines) || len(lines) != 3 || !errors.As(err, &lineErr) || lineErr.Line != 6 {
+		t.Fatal("expected the errors of the lines, got:", err)
+	}
+	if len(batchTestSums) != 3 || batchTestSums[2] != 7 {
+		t.Fatal("wrong lines executed:", batchTestSums)
+	}
+	expect := "error: line 6: failed\n" +
+		"error: line 7: no such flag: --nope\n" +
+		"error: line 8: script: already running a script\n"
+	if errOut != expect {
+		t.Fatalf("wrong errors: %q", errOut)
+	}
+	// the root running the script keeps its values after the lines
+	if strings.Join(batchTestLabels, ",") != ",,line,,," || root.Label != "outer" || !root.KeepGoing {
+		t.Fatal("wrong labels after the script:", batchTestLabels, root.Label)
+	}
+	words := [][]string{
+		{"$BATCH_TEST_NUM", "5", "$BATCH_TEST_NUM", "$BATCH_TEST_NUM"},
+		{"a b", "it's", "x --twice", ""},
+		{"5x", "$BATCH_TEST_NUMx", "", ""},
+	}
+	if !reflect.DeepEqual(batchTestWords, words) {
+		t.Fatalf("wrong words: %q", batchTestWords)
+	}
+
+	mapper := WithExitCodeMapper(func(err error) int {
+		if err.Error() == "failed" {
+			return 42
+		}
+		return ExitOK
+	})
+	_, err = run([]Option{mapper, WithErrorSelection(ErrorFirst)}, "--batch", "--keep-going")
+	if err == nil || ExitCode(err) != 42 {
+		t.Fatal("expected exit code of the mapper, got:", err)
+	}
+
+	ok := filepath.Join(t.TempDir(), "ok.txt")
+	if err := os.WriteFile(ok, []byte("--label line add 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(nil, "--label", "outer", "-f", ok); err != nil || strings.Join(batchTestLabels, ",") != "line,outer" {
+		t.Fatal("wrong labels of the post runs:", err, batchTestLabels)
+	}
+}
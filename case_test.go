@@ -97,7 +97,7 @@ func TestEnvDefaultCase(t *testing.T) {
 		if a == nil {
 			t.Fatal("flag should exist", c.Flag)
 		}
-		if a.env != c.Env {
+		if len(a.env) != 1 || a.env[0].name != c.Env {
 			t.Fatal(a.env, "!=", c.Env)
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -27,6 +28,11 @@ type Versioner interface {
 	Version() string
 }
 
+// Aliaser is implemented by commands having alternative names
+type Aliaser interface {
+	Aliases() []string
+}
+
 var defaultCLI = NewCLI()
 
 var enums = map[reflect.Type]*enum{}
@@ -35,19 +41,32 @@ var enums = map[reflect.Type]*enum{}
 type CLI struct {
 	options     *cliOptions
 	roots       []reflect.Value
+	parsed      bool
 	cmds        map[string]*command
+	providers   map[reflect.Type]reflect.Value
 	helpOut     io.Writer
 	errorOut    io.Writer
 	completeOut io.Writer
+	stdin       io.Reader
+	stdout      io.Writer
+	isTerminal  func(r io.Reader) bool
 	runList     []interface{}
+	cmdList     []*command
+	errCmd      *command
 	osExit      func(int)
+
+	signalNotify func(c chan<- os.Signal, sig ...os.Signal)
 }
 
 // NewCLI create new CLI
 func NewCLI(options ...Option) *CLI {
 	cli := &CLI{
-		cmds:   map[string]*command{},
-		osExit: os.Exit,
+		cmds:      map[string]*command{},
+		providers: map[reflect.Type]reflect.Value{},
+		osExit:    os.Exit,
+
+		isTerminal:   readerIsTerminal,
+		signalNotify: signal.Notify,
 	}
 	opts := &cliOptions{
 		argCase:     CaseCamelLower,
@@ -58,6 +77,9 @@ func NewCLI(options ...Option) *CLI {
 		helpLong:    "--help",
 		helpShort:   "-h",
 		versionLong: "--version",
+		aliases:     map[string]string{},
+
+		suggestDistance: 2,
 	}
 	for _, o := range options {
 		o(opts)
@@ -83,6 +105,39 @@ func NewCLI(options ...Option) *CLI {
 	if opts.tags.Complete == "" {
 		opts.tags.Complete = "complete"
 	}
+	if opts.tags.Min == "" {
+		opts.tags.Min = "min"
+	}
+	if opts.tags.Max == "" {
+		opts.tags.Max = "max"
+	}
+	if opts.tags.MinLen == "" {
+		opts.tags.MinLen = "minlen"
+	}
+	if opts.tags.MaxLen == "" {
+		opts.tags.MaxLen = "maxlen"
+	}
+	if opts.tags.Pattern == "" {
+		opts.tags.Pattern = "pattern"
+	}
+	if opts.tags.OneOf == "" {
+		opts.tags.OneOf = "oneof"
+	}
+	if opts.tags.RequiredIf == "" {
+		opts.tags.RequiredIf = "requiredIf"
+	}
+	if opts.tags.Excludes == "" {
+		opts.tags.Excludes = "excludes"
+	}
+	if opts.tags.Inject == "" {
+		opts.tags.Inject = "inject"
+	}
+	if opts.tags.Alias == "" {
+		opts.tags.Alias = "alias"
+	}
+	if opts.tags.Prompt == "" {
+		opts.tags.Prompt = "prompt"
+	}
 	if !(opts.separator == SeparatorEquals || opts.separator == SeparatorSpace) {
 		opts.separator = SeparatorSpace
 	}
@@ -98,6 +153,9 @@ func NewCLI(options ...Option) *CLI {
 	cli.options = opts
 	cli.completeOut = os.Stdout
 	cli.helpOut = os.Stdout
+	cli.errorOut = os.Stderr
+	cli.stdin = os.Stdin
+	cli.stdout = os.Stdout
 	return cli
 }
 
@@ -152,6 +210,7 @@ func (cli *CLI) NewCommand(name string, cmd interface{}) {
 	}
 	cli.cmds[name] = c
 	cli.walkStruct(c, t, path, "", "", false, strset.New())
+	c.linkConditions()
 }
 
 // Parse marshal string args to struct using the defaultCLI
@@ -168,33 +227,84 @@ func isCompletion() bool {
 // Parse marshal string args to struct
 func (cli *CLI) Parse(args []string) (err error) {
 
+	// parsing again starts from the initial state of the structs
+	if cli.parsed {
+		cli.Reset()
+	}
+	cli.snapshot()
+	cli.parsed = true
+
 	p := newParser(cli)
 
+	// keep the command where parsing failed to render its usage
+	defer func() {
+		if err != nil {
+			cli.errCmd = p.currentCmd()
+		}
+	}()
+
 	if err := p.Run(args); err != nil {
 		return err
 	}
 
-	// check for required and set default value
-	for _, a := range p.currentCmd().Flags() {
-		if err := a.SetEnv(); err != nil {
-			return err
+	// set the parent fields of the command chain
+	for _, c := range p.CmdList() {
+		c.SetParentFields()
+	}
+
+	// unless errors are collected parsing stops at the first error
+	errs := ParseErrors{}
+	stop := func(err error) bool {
+		if err != nil {
+			errs = append(errs, err)
 		}
-		if !a.IsSet() {
-			if a.required {
-				return fmt.Errorf("required flag not set: %s", a.long)
+		return len(errs) > 0 && !cli.options.collectErrors
+	}
+
+	// set env, prompted & default values and check for required on every
+	// command in the chain
+	pr := cli.newPrompter()
+	for _, c := range p.CmdList() {
+		for _, a := range c.Args() {
+			if stop(a.Resolve(cli.errorOut, pr)) {
+				return errs[0]
 			}
-			if err := a.SetDefaultValue(); err != nil {
-				panic("failed to set default value for flag: " + a.long)
+		}
+	}
+
+	// validate final values
+	for _, c := range p.CmdList() {
+		for _, a := range c.Args() {
+			if stop(a.Validate()) {
+				return errs[0]
 			}
 		}
 	}
-	for _, a := range p.currentCmd().Positionals() {
-		if a.required && !a.IsSet() {
-			return fmt.Errorf("required argument not set: %s", a.placeholder)
+
+	// check flag groups & conditional requirements
+	for _, c := range p.CmdList() {
+		for _, err := range append(c.CheckGroups(), c.CheckConditions()...) {
+			if stop(err) {
+				return errs[0]
+			}
 		}
 	}
 
+	// run validators of argument structs & commands
+	for _, c := range p.CmdList() {
+		for _, err := range c.Validate() {
+			if stop(err) {
+				return errs[0]
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
 	cli.runList = p.RunList()
+	cli.cmdList = p.CmdList()
 
 	return nil
 }
@@ -252,6 +362,24 @@ func (cli *CLI) walkStruct(
 			continue
 		}
 
+		// field set to an ancestor command
+		if tags.Cli.parent {
+			if isArg {
+				panic("parent field in argument struct: " + fldName)
+			}
+			c.AddParentField(pth.Subpath(fldName), fldType)
+			continue
+		}
+
+		// field set by a provider
+		if tags.Inject {
+			if isArg {
+				panic("inject field in argument struct: " + fldName)
+			}
+			c.injectFlds = append(c.injectFlds, pth.Subpath(fldName))
+			continue
+		}
+
 		// compute arg name, TODO: optimize
 		name := cli.options.argCase.Parse(fldName)
 		if tags.LongIsIgnored() {
@@ -289,11 +417,13 @@ func (cli *CLI) walkStruct(
 			// we know is an arg so use the name as prefix
 			if isArg {
 				cli.walkStruct(c, fldType, spth, name, env, isArg, globals)
+				c.argStructs = append(c.argStructs, spth)
 				continue
 			}
 			// is a ptr to struct but nocmd in tag is set or is a normal struct then this is an arg
 			if tags.CmdIsIgnored() || !isPtr(fldType) {
 				cli.walkStruct(c, fldType, spth, name, env, true, globals)
+				c.argStructs = append(c.argStructs, spth)
 				continue
 			}
 			// parse struct as a command
@@ -303,6 +433,16 @@ func (cli *CLI) walkStruct(
 			}
 			// add subcommand to the current command
 			sc := c.AddSubcommand(cname, spth, fld.Tag.Get(cli.options.tags.Usage))
+			if tags.Alias != "" {
+				for _, a := range strings.Split(tags.Alias, ",") {
+					sc.AddAlias(a)
+				}
+			}
+			if al, ok := reflect.New(fldType.Elem()).Interface().(Aliaser); ok {
+				for _, a := range al.Aliases() {
+					sc.AddAlias(a)
+				}
+			}
 			// down the rabbit hole we go
 			cli.walkStruct(sc, fldType, spth, "", "", false, globals.Copy())
 			continue
@@ -318,6 +458,23 @@ func (cli *CLI) walkStruct(
 			env = tags.Env.name
 		}
 
+		// positionals read env only with an env tag
+		if tags.Cli.positional && tags.Env.name == "" {
+			env = ""
+		}
+
+		// env var aliases are looked up in order after the primary name
+		envs := []envVar{}
+		if env != "" {
+			envs = append(envs, envVar{name: env})
+		}
+		for _, alias := range tags.Env.aliases {
+			if envpfx != "" && !tags.Env.explicit {
+				alias.name = cli.options.envSplicer.Splice(envpfx, alias.name)
+			}
+			envs = append(envs, alias)
+		}
+
 		// generate long and short flags
 		long := "--" + name
 		short := ""
@@ -353,10 +510,12 @@ func (cli *CLI) walkStruct(
 			typ:         fldType,
 			long:        long,
 			short:       short,
-			env:         env,
+			env:         envs,
 			required:    tags.Cli.required,
 			positional:  tags.Cli.positional,
 			global:      tags.Cli.global,
+			secret:      tags.Cli.secret,
+			prompt:      tags.Prompt,
 			help:        fld.Tag.Get(cli.options.tags.Usage),
 			placeholder: strings.ToUpper(name),
 		}
@@ -393,6 +552,9 @@ func (cli *CLI) walkStruct(
 			}
 		}
 
+		// validation
+		a.setValidation(tags)
+
 		// default value
 		if def := fld.Tag.Get(cli.options.tags.Default); def != "" {
 			defval := []string{def}
@@ -410,6 +572,22 @@ func (cli *CLI) walkStruct(
 		if added := c.AddArg(a); !added {
 			panic(fmt.Sprintf("flag name already added for command: %s long: %s short: %s", c.Name, a.long, a.short))
 		}
+
+		// conditional requirements, linked to the flags after the walk
+		if tags.RequiredIf != "" {
+			a.requiredIfTag = strings.Split(tags.RequiredIf, ",")
+		}
+		if tags.Excludes != "" {
+			a.excludesTag = strings.Split(tags.Excludes, ",")
+		}
+
+		// add the argument to its flag groups
+		for _, g := range tags.Cli.groups {
+			if a.positional {
+				panic("positional argument in flag group: " + a.Name())
+			}
+			c.AddToGroup(g.kind, g.name, a)
+		}
 	}
 }
 
@@ -419,7 +597,7 @@ func (cli *CLI) findRootCommand(name string) (*command, error) {
 		// try base path
 		c, ok = cli.cmds[filepath.Base(name)]
 		if !ok {
-			return nil, ErrCommandNotFound{name}
+			return nil, ErrCommandNotFound{Command: name}
 		}
 	}
 	return c, nil
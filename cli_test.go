@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -533,3 +537,903 @@ func TestEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestEnvAliases(t *testing.T) {
+
+	args := &struct {
+		DB struct {
+			URL string `env:"URL|DATABASE_URL:deprecated"`
+		}
+		Token string `env:"TOKEN|API_TOKEN|ACCESS_TOKEN:deprecated"`
+	}{}
+
+	os.Setenv("DB_DATABASE_URL", "postgres://db")
+	os.Setenv("API_TOKEN", "secret")
+	os.Setenv("ACCESS_TOKEN", "legacy")
+	defer os.Unsetenv("DB_DATABASE_URL")
+	defer os.Unsetenv("API_TOKEN")
+	defer os.Unsetenv("ACCESS_TOKEN")
+
+	c := NewCLI()
+	buf := &bytes.Buffer{}
+	c.errorOut = buf
+	c.NewCommand("root", args)
+
+	if err := c.Parse([]string{"root"}); err != nil {
+		t.Fatal("failed to parse args: " + err.Error())
+	}
+
+	if args.DB.URL != "postgres://db" {
+		t.Fatal("DB.URL should have been postgres://db")
+	}
+	if args.Token != "secret" {
+		t.Fatal("Token should have been secret")
+	}
+	expect := "warning: environment variable DB_DATABASE_URL is deprecated, use DB_URL instead\n"
+	if buf.String() != expect {
+		t.Fatalf("wrong warning: %q", buf.String())
+	}
+	if names := c.cmds["root"].GetFlag("--token").EnvNames(); strings.Join(names, ",") != "TOKEN,API_TOKEN" {
+		t.Fatal("wrong current env names:", names)
+	}
+
+	os.Unsetenv("API_TOKEN")
+	buf.Reset()
+	if err := c.Parse([]string{"root"}); err != nil {
+		t.Fatal("failed to parse args: " + err.Error())
+	}
+	expect = "warning: environment variable DB_DATABASE_URL is deprecated, use DB_URL instead\n" +
+		"warning: environment variable ACCESS_TOKEN is deprecated, use TOKEN instead\n"
+	if args.Token != "legacy" || buf.String() != expect {
+		t.Fatalf("wrong value %q or warnings: %q", args.Token, buf.String())
+	}
+}
+
+func TestEnvCommandChain(t *testing.T) {
+	type subcmd struct {
+		Name  string `cli:"positional,required" env:"NAME"`
+		Count int    `cli:"positional" default:"3"`
+	}
+	args := &struct {
+		Token  string `env:"TOKEN"`
+		Subcmd *subcmd
+	}{}
+
+	os.Setenv("TOKEN", "secret")
+	defer os.Unsetenv("TOKEN")
+
+	c := NewCLI()
+	c.NewCommand("root", args)
+
+	err := c.Parse([]string{"root", "subcmd"})
+	if err == nil || err.Error() != "required argument not set: NAME (env: NAME)" {
+		t.Fatal("should have failed with required argument error, got:", err)
+	}
+
+	os.Setenv("NAME", "tester")
+	defer os.Unsetenv("NAME")
+
+	if err := c.Parse([]string{"root", "subcmd"}); err != nil {
+		t.Fatal("failed to parse args: " + err.Error())
+	}
+	if args.Token != "secret" {
+		t.Fatal("Token should have been secret")
+	}
+	if args.Subcmd.Name != "tester" {
+		t.Fatal("Subcmd.Name should have been tester")
+	}
+	if args.Subcmd.Count != 3 {
+		t.Fatal("Subcmd.Count should have been 3")
+	}
+
+	// positionals read env only with an env tag
+	t.Setenv("PATH", "/bin")
+	pathArgs := &struct {
+		Path string `cli:"positional,required"`
+	}{}
+	c = NewCLI()
+	c.NewCommand("root", pathArgs)
+	err = c.Parse([]string{"root"})
+	if err == nil || err.Error() != "required argument not set: PATH" {
+		t.Fatal("should have failed with required argument error, got:", err)
+	}
+}
+
+func TestValidationTags(t *testing.T) {
+	type validationArgs struct {
+		Port   int      `min:"1" max:"65535" default:"8080"`
+		Name   string   `pattern:"^[a-z]+$" minlen:"2" maxlen:"8"`
+		Output string   `oneof:"json,yaml,table"`
+		Hosts  []string `maxlen:"2"`
+	}
+
+	cases := []struct {
+		Args []string
+		Env  string
+		Err  string
+	}{
+		{[]string{"root", "--name", "tester", "--output", "yaml"}, "", ""},
+		{[]string{"root", "--port", "0"}, "", "invalid value: 0 for --port (from command line): must be at least 1"},
+		{[]string{"root"}, "70000", "invalid value: 70000 for --port (from env PORT): must be at most 65535"},
+		{[]string{"root", "--name", "Tester"}, "", "invalid value: Tester for --name (from command line): must match ^[a-z]+$"},
+		{[]string{"root", "--name", "a"}, "", "invalid value: a for --name (from command line): must be at least 2 characters"},
+		{[]string{"root", "--output", "xml"}, "", "invalid value: xml for --output (from command line): must be one of: json, yaml, table"},
+		{[]string{"root", "--output", "JSON"}, "", "invalid value: JSON for --output (from command line): must be one of: json, yaml, table"},
+		{[]string{"root", "--hosts", "a", "--hosts", "b", "--hosts", "c"}, "", "invalid value: a b c for --hosts (from command line): must have at most 2 values"},
+	}
+
+	for _, c := range cases {
+		if c.Env != "" {
+			os.Setenv("PORT", c.Env)
+		}
+		p := NewCLI()
+		p.NewCommand("root", &validationArgs{})
+		err := p.Parse(c.Args)
+		os.Unsetenv("PORT")
+		if c.Err == "" {
+			if err != nil {
+				t.Fatal(c.Args, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.Err {
+			t.Fatalf("%v: expected %q got %v", c.Args, c.Err, err)
+		}
+	}
+
+	p := NewCLI()
+	p.NewCommand("root", &validationArgs{})
+	out := p.cmds["root"].GetFlag("--output").Complete("j")
+	if len(out) != 1 || out[0] != "json " {
+		t.Fatal("oneof should complete json", out)
+	}
+
+	RegisterEnum(map[string]validationTestLevel{
+		"low":  1,
+		"mid":  2,
+		"high": 3,
+	})
+	enumArgs := &struct {
+		Level validationTestLevel `oneof:"low,high"`
+	}{}
+	p = NewCLI()
+	p.NewCommand("root", enumArgs)
+	if err := p.Parse([]string{"root", "--level", "low"}); err != nil || enumArgs.Level != 1 {
+		t.Fatal("oneof should match enum names, got:", err)
+	}
+	if err := p.Parse([]string{"root", "--level", "mid"}); err == nil {
+		t.Fatal("oneof should reject enum names not listed")
+	}
+}
+
+type validationTestLevel int
+
+type validatorTLS struct {
+	Cert string
+	Key  string
+}
+
+func (v *validatorTLS) Validate() error {
+	if (v.Cert == "") != (v.Key == "") {
+		return errors.New("cert and key must be set together")
+	}
+	return nil
+}
+
+type validatorCmd struct {
+	TLS   validatorTLS
+	Users []string
+	Admin string
+}
+
+func (c *validatorCmd) Validate() error {
+	for _, u := range c.Users {
+		if u == c.Admin {
+			return nil
+		}
+	}
+	return errors.New("admin must be one of the users")
+}
+
+func TestValidator(t *testing.T) {
+	args := &struct {
+		Subcmd *validatorCmd
+	}{}
+
+	p := NewCLI()
+	p.NewCommand("root", args)
+
+	err := p.Parse([]string{"root", "subcmd", "--tls.cert", "c", "--users", "a", "--admin", "a"})
+	verr := ErrValidation{}
+	if !errors.As(err, &verr) || verr.Command != "subcmd" || err.Error() != "cert and key must be set together" {
+		t.Fatal("should have failed tls validation, got:", err)
+	}
+
+	args.Subcmd = nil
+	p = NewCLI()
+	p.NewCommand("root", args)
+	err = p.Parse([]string{"root", "subcmd", "--users", "a", "--admin", "b"})
+	if err == nil || err.Error() != "admin must be one of the users" {
+		t.Fatal("should have failed admin validation, got:", err)
+	}
+}
+
+func TestFlagGroups(t *testing.T) {
+	type groupArgs struct {
+		JSON     bool   `cli:"xor=output"`
+		YAML     bool   `cli:"xor=output"`
+		File     string `cli:"xor=source,oneRequired=source"`
+		Stdin    bool   `cli:"xor=source,oneRequired=source"`
+		User     string `cli:"and=auth"`
+		Password string `cli:"and=auth"`
+	}
+
+	cases := []struct {
+		Args []string
+		Err  string
+	}{
+		{[]string{"root", "--stdin", "--json"}, ""},
+		{[]string{"root", "--file", "f", "--user", "u", "--password", "p"}, ""},
+		{[]string{"root", "--stdin", "--json", "--yaml"}, "flags --json, --yaml are mutually exclusive"},
+		{[]string{"root"}, "one of the flags --file, --stdin is required"},
+		{[]string{"root", "--stdin", "--file", "f"}, "flags --file, --stdin are mutually exclusive"},
+		{[]string{"root", "--stdin", "--user", "u"}, "flags --user, --password must be set together: --password not set"},
+	}
+
+	for _, c := range cases {
+		p := NewCLI()
+		p.NewCommand("root", &groupArgs{})
+		err := p.Parse(c.Args)
+		if c.Err == "" {
+			if err != nil {
+				t.Fatal(c.Args, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.Err {
+			t.Fatalf("%v: expected %q got %v", c.Args, c.Err, err)
+		}
+	}
+
+	p := NewCLI()
+	p.NewCommand("root", &groupArgs{})
+	usage := strings.Join(p.cmds["root"].FlagsUsage(), " ")
+	expect := "[--json | --yaml] (--file FILE | --stdin) [--user USER --password PASSWORD]"
+	if usage != expect {
+		t.Fatalf("wrong usage: %q", usage)
+	}
+}
+
+func TestConditionalRequirements(t *testing.T) {
+	type conditionArgs struct {
+		TLS     bool
+		TLSCert string `long:"tls-cert" requiredIf:"tls"`
+		Storage string `default:"local"`
+		Bucket  string `requiredIf:"storage=s3"`
+		Dir     string `excludes:"storage=s3"`
+	}
+
+	cases := []struct {
+		Args []string
+		Err  string
+	}{
+		{[]string{"root", "--tls", "--tls-cert", "cert.pem"}, ""},
+		{[]string{"root", "--storage", "s3", "--bucket", "b"}, ""},
+		{[]string{"root", "--dir", "/data"}, ""},
+		{[]string{"root", "--tls"}, "--tls-cert is required when --tls is set"},
+		{[]string{"root", "--storage", "s3"}, "--bucket is required when --storage=s3"},
+		{[]string{"root", "--storage", "s3", "--bucket", "b", "--dir", "/data"}, "--dir cannot be used when --storage=s3"},
+	}
+
+	for _, c := range cases {
+		p := NewCLI()
+		p.NewCommand("root", &conditionArgs{})
+		err := p.Parse(c.Args)
+		if c.Err == "" {
+			if err != nil {
+				t.Fatal(c.Args, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.Err {
+			t.Fatalf("%v: expected %q got %v", c.Args, c.Err, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	type errArgs struct {
+		Port  int    `max:"100"`
+		Name  string `cli:"required"`
+		Level int8
+		Tags  []string
+		File  string `cli:"positional"`
+	}
+
+	p := NewCLI()
+	p.NewCommand("root", &errArgs{})
+
+	err := p.Parse([]string{"root", "--name"})
+	missing := ErrMissingValue{}
+	if !errors.As(err, &missing) || missing.Flag != "--name" || missing.Index != 1 {
+		t.Fatal("should have failed with missing value, got:", err)
+	}
+
+	err = p.Parse([]string{"root", "--port", "--name", "x"})
+	if !errors.As(err, &missing) || missing.Flag != "--port" {
+		t.Fatal("should have failed with missing value, got:", err)
+	}
+
+	err = p.Parse([]string{"root", "--name", "x", "--level", "300"})
+	invalid := ErrInvalidValue{}
+	if !errors.As(err, &invalid) || invalid.Flag != "--level" || invalid.Value != "300" || invalid.Source != "command line" {
+		t.Fatal("should have failed with invalid value, got:", err)
+	}
+
+	err = p.Parse([]string{"root", "--name", "x", "a", "b"})
+	tooMany := ErrTooManyPositionals{}
+	if !errors.As(err, &tooMany) || tooMany.Value != "b" || tooMany.Index != 4 {
+		t.Fatal("should have failed with too many positionals, got:", err)
+	}
+
+	t.Setenv("TAGS", "'x")
+	err = p.Parse([]string{"root", "--name", "x"})
+	if !errors.As(err, &invalid) || invalid.Flag != "--tags" || invalid.Value != "'x" || invalid.Source != "env TAGS" {
+		t.Fatal("should have failed with invalid env value, got:", err)
+	}
+	os.Unsetenv("TAGS")
+
+	p = NewCLI(WithCollectErrors())
+	p.NewCommand("root", &errArgs{})
+	err = p.Parse([]string{"root", "--port", "200"})
+	perrs := ParseErrors{}
+	if !errors.As(err, &perrs) || len(perrs) != 2 {
+		t.Fatal("should have collected 2 errors, got:", err)
+	}
+	required := ErrRequired{}
+	if !errors.As(err, &required) || required.Name != "--name" {
+		t.Fatal("should have collected required error, got:", err)
+	}
+	if !errors.As(err, &invalid) || invalid.Flag != "--port" {
+		t.Fatal("should have collected invalid value error, got:", err)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	type subcmd struct {
+		Namespace string
+		Name      string
+	}
+	args := &struct {
+		Deploy  *subcmd
+		Destroy *subcmd
+		Status  *subcmd
+	}{}
+
+	p := NewCLI()
+	p.NewCommand("root", args)
+
+	err := p.Parse([]string{"root", "deplyo"})
+	notFound := ErrCommandNotFound{}
+	if !errors.As(err, &notFound) || len(notFound.Suggestions) != 1 || notFound.Suggestions[0] != "deploy" {
+		t.Fatal("should have suggested deploy, got:", err)
+	}
+	if err.Error() != "command not found: deplyo, did you mean deploy?" {
+		t.Fatal("wrong error message:", err)
+	}
+
+	err = p.Parse([]string{"root", "deploy", "--namspace", "x"})
+	noFlag := ErrNoSuchFlag{}
+	if !errors.As(err, &noFlag) || len(noFlag.Suggestions) != 1 || noFlag.Suggestions[0] != "--namespace" {
+		t.Fatal("should have suggested --namespace, got:", err)
+	}
+
+	p = NewCLI(WithSuggestionDistance(0))
+	p.NewCommand("root", args)
+	err = p.Parse([]string{"root", "deplyo"})
+	if err == nil || err.Error() != "command not found: deplyo" {
+		t.Fatal("should not have suggested, got:", err)
+	}
+}
+
+type aliasTestStatus struct{}
+
+func (c *aliasTestStatus) Aliases() []string {
+	return []string{"st"}
+}
+
+func TestCommandAliases(t *testing.T) {
+	type subcmd struct {
+		Name string
+	}
+	args := &struct {
+		Deploy  *subcmd `alias:"dep"`
+		Destroy *subcmd
+		Remove  *subcmd `alias:"rm,del"`
+		Status  *aliasTestStatus
+	}{}
+
+	p := NewCLI(WithPrefixMatching())
+	p.NewCommand("root", args)
+
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"root", "rm"}, "remove"},
+		{[]string{"root", "del"}, "remove"},
+		{[]string{"root", "st"}, "status"},
+		{[]string{"root", "deploy"}, "deploy"},
+		{[]string{"root", "dep"}, "deploy"},
+		{[]string{"root", "depl"}, "deploy"},
+		{[]string{"root", "dest"}, "destroy"},
+		{[]string{"root", "stat"}, "status"},
+		{[]string{"root", "r"}, "remove"},
+	}
+	for _, c := range cases {
+		if err := p.Parse(c.args); err != nil {
+			t.Fatal(c.args, err)
+		}
+		if p.cmdList[1].Name != c.expect {
+			t.Fatal("wrong command for", c.args)
+		}
+	}
+
+	err := p.Parse([]string{"root", "de"})
+	ambiguous := ErrAmbiguousCommand{}
+	if !errors.As(err, &ambiguous) || strings.Join(ambiguous.Candidates, ",") != "deploy,destroy,remove" {
+		t.Fatal("expected ambiguous command, got:", err)
+	}
+
+	buf := &bytes.Buffer{}
+	p.cmds["root"].Usage(buf)
+	if !strings.Contains(buf.String(), "(aliases: rm, del)") {
+		t.Fatal("aliases not in help:", buf.String())
+	}
+
+	p = NewCLI()
+	p.NewCommand("root", args)
+	err = p.Parse([]string{"root", "dep"})
+	if err != nil || p.cmdList[1].Name != "deploy" {
+		t.Fatal("aliases should not need prefix matching:", err)
+	}
+	err = p.Parse([]string{"root", "depl"})
+	notFound := ErrCommandNotFound{}
+	if !errors.As(err, &notFound) {
+		t.Fatal("prefixes should not match without prefix matching, got:", err)
+	}
+
+	conflict := &struct {
+		Remove *struct{} `alias:"rm"`
+		Rm     *struct{}
+	}{}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("should have paniced on command named as an alias")
+		}
+	}()
+	NewCLI().NewCommand("root", conflict)
+}
+
+func TestFlagAbbreviation(t *testing.T) {
+	args := &struct {
+		Verbose bool   `cli:"global"`
+		Version bool   `cli:"global"`
+		Output  string `cli:"global"`
+		Run     *struct {
+			Out     string
+			Timeout int
+		}
+	}{}
+
+	p := NewCLI(WithFlagAbbreviation(), WithGlobalArgsEnabled())
+	p.NewCommand("root", args)
+
+	if err := p.Parse([]string{"root", "--verb", "--outp", "a", "run", "--out", "b", "--time=3", "--outpu", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if !args.Verbose || args.Version || args.Output != "c" || args.Run.Out != "b" || args.Run.Timeout != 3 {
+		t.Fatal("wrong values:", args.Verbose, args.Version, args.Output, args.Run.Out, args.Run.Timeout)
+	}
+
+	err := p.Parse([]string{"root", "--ver"})
+	ambiguous := ErrAmbiguousFlag{}
+	if !errors.As(err, &ambiguous) || strings.Join(ambiguous.Candidates, ",") != "--verbose,--version" {
+		t.Fatal("expected ambiguous flag, got:", err)
+	}
+
+	p = NewCLI()
+	p.NewCommand("root", args)
+	err = p.Parse([]string{"root", "--verb"})
+	noFlag := ErrNoSuchFlag{}
+	if !errors.As(err, &noFlag) {
+		t.Fatal("flags should not be abbreviated by default, got:", err)
+	}
+}
+
+func TestUserAliases(t *testing.T) {
+	type status struct {
+		Short bool
+		Dir   string
+	}
+	newArgs := func() *struct {
+		Debug  bool
+		Status *status
+		Log    *status
+	} {
+		return &struct {
+			Debug  bool
+			Status *status
+			Log    *status
+		}{}
+	}
+
+	cfg := `
+; user config
+[core]
+st = log
+
+[alias]
+# shortcuts
+st = status --short
+dst = --debug st --dir 'my dir'
+log = status
+loop = loop2
+loop2 = loop
+`
+	args := newArgs()
+	p := NewCLI(WithAliases(map[string]string{"s": "status"}))
+	if err := p.LoadAliases(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	p.NewCommand("root", args)
+
+	if err := p.Parse([]string{"root", "s"}); err != nil || args.Status == nil || args.Status.Short {
+		t.Fatal("alias from option not expanded:", err)
+	}
+
+	args = newArgs()
+	p.NewCommand("root", args)
+	if err := p.Parse([]string{"root", "dst", "--short=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if !args.Debug || args.Status == nil || args.Status.Short || args.Status.Dir != "my dir" {
+		t.Fatal("alias not expanded recursively:", args.Debug, args.Status)
+	}
+
+	// subcommands take precedence
+	args = newArgs()
+	p.NewCommand("root", args)
+	if err := p.Parse([]string{"root", "log"}); err != nil || args.Log == nil || args.Status != nil {
+		t.Fatal("subcommand should shadow alias:", err)
+	}
+
+	if err := p.Parse([]string{"root", "loop"}); err == nil || err.Error() != "recursive alias: loop" {
+		t.Fatal("expected recursive alias error, got:", err)
+	}
+
+	buf := &bytes.Buffer{}
+	p.cmds["root"].Usage(buf)
+	if !strings.Contains(buf.String(), "Aliases:") || !strings.Contains(buf.String(), "st           = status --short") {
+		t.Fatal("aliases not in help:", buf.String())
+	}
+	if strings.Join(p.cmds["root"].CompleteSubcommands("s"), ",") != "status ,s ,st " {
+		t.Fatal("wrong completion:", p.cmds["root"].CompleteSubcommands("s"))
+	}
+
+	if err := p.LoadAliases(strings.NewReader("[alias]\nbad alias\n")); err == nil {
+		t.Fatal("expected invalid alias error")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		A, B string
+		Dist int
+	}{
+		{"deploy", "deploy", 0},
+		{"deploy", "deplyo", 1},
+		{"namespace", "namspace", 1},
+		{"status", "stats", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range cases {
+		if d := editDistance(c.A, c.B); d != c.Dist {
+			t.Fatalf("distance %s %s: %d != %d", c.A, c.B, d, c.Dist)
+		}
+	}
+}
+
+type mainTestCmd struct {
+	Fail  bool
+	Count int
+}
+
+type mainTestErr struct{}
+
+func (mainTestErr) Error() string { return "failed" }
+func (mainTestErr) ExitCode() int { return 42 }
+
+func (c *mainTestCmd) Run(ctx context.Context) error {
+	if c.Fail {
+		return mainTestErr{}
+	}
+	return nil
+}
+
+func TestCLIMain(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	cases := []struct {
+		Args    []string
+		Options []Option
+		Code    int
+		Output  string
+	}{
+		{[]string{"app"}, nil, -1, ""},
+		{[]string{"app", "--fail"}, nil, 42, "error: failed\n"},
+		{[]string{"app", "@" + missing}, []Option{WithResponseFiles('@')}, 2, "error: response file: open " + missing + ": no such file or directory\n"},
+		{[]string{"app", "--count", "x"}, nil, 2, `error: invalid value: x for --count (from command line): invalid syntax
+
+Usage:
+    app [--fail] [--count COUNT]
+
+Run 'app --help' for more information.
+`},
+	}
+
+	for _, c := range cases {
+		os.Args = c.Args
+		p := NewCLI(c.Options...)
+		buf := &bytes.Buffer{}
+		p.errorOut = buf
+		code := -1
+		p.osExit = func(i int) { code = i }
+		p.Main(context.Background(), &mainTestCmd{})
+		if code != c.Code {
+			t.Fatalf("%v: exit code %d != %d", c.Args, code, c.Code)
+		}
+		if buf.String() != c.Output {
+			t.Fatalf("%v: wrong output: %q", c.Args, buf.String())
+		}
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	inner := write("inner.txt", "--name 'big name'\n")
+	outer := write("outer.txt", "--tags a --tags b\n@"+inner+"\n")
+	loop := filepath.Join(dir, "loop.txt")
+	write("loop.txt", "@"+loop)
+
+	args := &struct {
+		Name  string
+		Tags  []string
+		Files []string `cli:"positional"`
+	}{}
+	p := NewCLI(WithResponseFiles('@'))
+	p.NewCommand("root", args)
+
+	if err := p.Parse([]string{"root", "@" + outer, "--", "@" + inner}); err != nil {
+		t.Fatal(err)
+	}
+	if args.Name != "big name" || strings.Join(args.Tags, ",") != "a,b" || len(args.Files) != 1 || args.Files[0] != "@"+inner {
+		t.Fatal("wrong values:", args.Name, args.Tags, args.Files)
+	}
+
+	if err := p.Parse([]string{"root", "@" + loop}); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Fatal("expected cycle error, got:", err)
+	}
+	if err := p.Parse([]string{"root", "@" + filepath.Join(dir, "missing.txt")}); err == nil {
+		t.Fatal("expected missing file error")
+	}
+
+	// completion
+	defer os.Unsetenv("COMP_LINE")
+	defer os.Unsetenv("COMP_POINT")
+	line := "root @" + dir + "/in"
+	os.Setenv("COMP_LINE", line)
+	os.Setenv("COMP_POINT", strconv.Itoa(len(line)))
+	buf := &bytes.Buffer{}
+	p.completeOut = buf
+	p.osExit = func(int) {}
+	p.Parse([]string{"root"})
+	if buf.String() != "@"+inner+" \n" {
+		t.Fatalf("wrong completion: %q", buf.String())
+	}
+}
+
+func TestReset(t *testing.T) {
+	type sub struct {
+		Tags  []string
+		Level int
+	}
+	args := &struct {
+		Verbose bool
+		Name    string
+		Tags    []string
+		Sub     *sub
+		Other   *sub
+		Out     *bytes.Buffer `cli:"-"`
+		count   int
+	}{
+		Name: "initial",
+		Out:  &bytes.Buffer{},
+	}
+	out := args.Out
+	p := NewCLI()
+	p.NewCommand("root", args)
+
+	if err := p.Parse([]string{"root", "--verbose", "--name", "x", "--tags", "a", "sub", "--tags", "b", "--level", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	first := args.Sub
+	args.Out.WriteString("kept")
+	args.count = 1
+	if err := p.Parse([]string{"root", "--tags", "c", "sub", "--tags", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if args.Verbose || args.Name != "initial" || strings.Join(args.Tags, ",") != "c" {
+		t.Fatal("root not reset:", args.Verbose, args.Name, args.Tags)
+	}
+	if args.Sub == first || strings.Join(args.Sub.Tags, ",") != "d" || args.Sub.Level != 0 {
+		t.Fatal("subcommand not reset:", args.Sub)
+	}
+	if p.runList[1] != args.Sub {
+		t.Fatal("run list should have the new subcommand struct")
+	}
+	if first.Level != 3 {
+		t.Fatal("previous struct should not be changed")
+	}
+	if args.Out != out || out.String() != "kept" || args.count != 1 {
+		t.Fatal("fields not set by parsing should be left alone")
+	}
+
+	if err := p.Parse([]string{"root", "other", "--level", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if args.Sub != nil || args.Other.Level != 1 {
+		t.Fatal("wrong subcommands after reset:", args.Sub, args.Other)
+	}
+	for _, a := range p.cmds["root"].subcmdsMap["sub"].Args() {
+		if a.IsSet() {
+			t.Fatal("argument of previous parse still set:", a.long)
+		}
+	}
+
+	p.Reset()
+	if args.Other != nil || args.Name != "initial" {
+		t.Fatal("Reset should restore the initial values")
+	}
+
+	// subpaths must not share their backing array
+	root := &path{path: make([]string, 1, 4)}
+	a, b := root.Subpath("a"), root.Subpath("b")
+	if a.path[1] != "a" || b.path[1] != "b" {
+		t.Fatal("subpaths share their backing array")
+	}
+}
+
+type promptTestLevel int
+
+func TestPrompting(t *testing.T) {
+	RegisterEnum(map[string]promptTestLevel{
+		"low":  1,
+		"high": 2,
+	})
+	type cmd struct {
+		Token  string          `cli:"required,secret" prompt:"Enter API token" default:"hidden"`
+		User   string          `cli:"required" default:"admin"`
+		Level  promptTestLevel `cli:"required"`
+		Port   int             `cli:"required"`
+		Format string          `cli:"required" oneof:"json,yaml"`
+		Host   string          `cli:"required"`
+	}
+	args := &cmd{}
+	input := strings.Join([]string{
+		"s3cr3t ",
+		"",
+		"2",
+		"abc",
+		"8080",
+		"yaml",
+	}, "\n")
+
+	p := NewCLI()
+	p.NewCommand("root", args)
+	out := &bytes.Buffer{}
+	p.errorOut = out
+	p.stdin = strings.NewReader(input)
+	p.isTerminal = func(io.Reader) bool { return true }
+	err := p.Parse([]string{"root", "--host", "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Token != "s3cr3t " || args.User != "admin" || args.Level != 1 || args.Port != 8080 || args.Format != "yaml" || args.Host != "localhost" {
+		t.Fatalf("wrong values: %+v", args)
+	}
+	expect := "Enter API token: " +
+		"user [admin]: " +
+		"  1) HIGH\n  2) LOW\n" +
+		"level: " +
+		"port: invalid value: abc for --port (from prompt): invalid syntax\n" +
+		"port: " +
+		"  1) json\n  2) yaml\n" +
+		"format: "
+	if out.String() != expect {
+		t.Fatalf("wrong prompts: %q", out.String())
+	}
+	if p.cmds["root"].GetFlag("--port").Source() != "prompt" {
+		t.Fatal("wrong source")
+	}
+
+	// not prompted without terminal, when disabled or at end of input
+	cases := []struct {
+		cli      *CLI
+		terminal bool
+	}{
+		{NewCLI(), false},
+		{NewCLI(WithoutPrompting()), true},
+		{NewCLI(), true},
+	}
+	for _, c := range cases {
+		terminal := c.terminal
+		c.cli.NewCommand("root", &cmd{})
+		c.cli.errorOut = &bytes.Buffer{}
+		c.cli.stdin = strings.NewReader("")
+		c.cli.isTerminal = func(io.Reader) bool { return terminal }
+		err := c.cli.Parse([]string{"root"})
+		required := ErrRequired{}
+		if !errors.As(err, &required) || required.Name != "--level" {
+			t.Fatal("expected required error, got:", err)
+		}
+	}
+
+	// numeric choices & invalid answers
+	numArgs := &struct {
+		Size string `cli:"required" oneof:"2,10"`
+		Port int    `cli:"required"`
+	}{}
+	p = NewCLI()
+	p.NewCommand("root", numArgs)
+	p.errorOut = &bytes.Buffer{}
+	p.stdin = strings.NewReader("2\nx\ny\nz\n")
+	p.isTerminal = func(io.Reader) bool { return true }
+	err = p.Parse([]string{"root"})
+	invalid := ErrInvalidValue{}
+	if !errors.As(err, &invalid) || invalid.Flag != "--port" || invalid.Value != "z" {
+		t.Fatal("expected invalid value error, got:", err)
+	}
+	if numArgs.Size != "2" {
+		t.Fatal("numeric choice should be kept, got:", numArgs.Size)
+	}
+
+	// secrets are left out of errors
+	secretArgs := &struct {
+		Pin int `cli:"required,secret"`
+	}{}
+	p = NewCLI()
+	p.NewCommand("root", secretArgs)
+	out = &bytes.Buffer{}
+	p.errorOut = out
+	p.stdin = strings.NewReader("hunter2\n")
+	p.isTerminal = func(io.Reader) bool { return true }
+	err = p.Parse([]string{"root"})
+	if !errors.As(err, &invalid) || invalid.Value != "" || strings.Contains(err.Error()+out.String(), "hunter2") {
+		t.Fatalf("secret value in error: %v %q", err, out.String())
+	}
+	t.Setenv("PIN", "hunter2")
+	err = p.Parse([]string{"root"})
+	if err == nil || err.Error() != "invalid secret value for --pin (from env PIN): invalid syntax" {
+		t.Fatal("secret value in error:", err)
+	}
+}
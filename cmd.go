@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"fmt"
 	"html/template"
 	"io"
 	"strings"
@@ -14,11 +15,17 @@ type command struct {
 	description string
 	group       string
 	hidden      bool
+	aliases     []string
 	flags       *flagSet
 	positionals []*argument
+	argStructs  []*path
+	groups      []*flagGroup
+	parentFlds  []parentField
+	injectFlds  []*path
 	subcmdsMap  map[string]*command
 	opts        *cliOptions
 	subcmds     []*command
+	initial     []fieldState
 }
 
 func (c *command) self() interface{} {
@@ -45,11 +52,23 @@ func (c *command) Flags() []*argument {
 	return c.flags.All()
 }
 
+// Args returns flags followed by positionals
+func (c *command) Args() []*argument {
+	out := make([]*argument, 0, len(c.flags.all)+len(c.positionals))
+	out = append(out, c.flags.all...)
+	return append(out, c.positionals...)
+}
+
 func (c *command) HasSubcommands() bool {
 	return len(c.subcmds) != 0
 }
 
+// AddSubcommand adds the subcommand name to the command. Panics if name is
+// already used by another subcommand or alias
 func (c *command) AddSubcommand(name string, p *path, help string) *command {
+	if _, ok := c.subcmdsMap[name]; ok {
+		panic(fmt.Sprintf("name already in use for command %s: %s", c.Name, name))
+	}
 	sc := &command{
 		path:       p,
 		parent:     c,
@@ -64,11 +83,49 @@ func (c *command) AddSubcommand(name string, p *path, help string) *command {
 	return sc
 }
 
+// AddAlias makes the command accessible by alias from its parent. Panics if
+// alias is already used by another subcommand
+func (c *command) AddAlias(alias string) {
+	if _, ok := c.parent.subcmdsMap[alias]; ok {
+		panic(fmt.Sprintf("alias already in use for command %s: %s", c.parent.Name, alias))
+	}
+	c.aliases = append(c.aliases, alias)
+	c.parent.subcmdsMap[alias] = c
+}
+
 func (c *command) LookupSubcommand(name string) (sc *command, ok bool) {
 	sc, ok = c.subcmdsMap[name]
 	return
 }
 
+// MatchSubcommands returns the visible subcommands with a name or alias
+// starting with prefix
+func (c *command) MatchSubcommands(prefix string) (out []*command) {
+	for _, sc := range c.subcmds {
+		if sc.hidden {
+			continue
+		}
+		for _, n := range append([]string{sc.Name}, sc.aliases...) {
+			if strings.HasPrefix(n, prefix) {
+				out = append(out, sc)
+				break
+			}
+		}
+	}
+	return
+}
+
+// SubcommandNames returns the names and aliases of the visible subcommands
+func (c *command) SubcommandNames() (out []string) {
+	for _, sc := range c.subcmds {
+		if !sc.hidden {
+			out = append(out, sc.Name)
+			out = append(out, sc.aliases...)
+		}
+	}
+	return
+}
+
 func (c *command) Description() string {
 	if desc, ok := c.self().(Descriptioner); ok {
 		return desc.Description()
@@ -81,23 +138,32 @@ func (c *command) SubcmdDescription() (out []string) {
 		if sc.hidden {
 			continue
 		}
-		b := strings.Builder{}
-		b.WriteString(sc.Name)
-		l := int(c.opts.cmdColSize)
-		if len(sc.Name) >= l {
-			b.WriteString("\n  ")
-		} else {
-			l -= len(sc.Name)
-		}
-		for i := 0; i < l; i++ {
-			b.WriteByte(' ')
+		desc := sc.help
+		if len(sc.aliases) > 0 {
+			desc += " (aliases: " + strings.Join(sc.aliases, ", ") + ")"
 		}
-		b.WriteString(sc.help)
-		out = append(out, b.String())
+		out = append(out, c.cmdColumns(sc.Name, desc))
 	}
 	return
 }
 
+// cmdColumns returns name padded to the command column followed by desc
+func (c *command) cmdColumns(name, desc string) string {
+	b := strings.Builder{}
+	b.WriteString(name)
+	l := int(c.opts.cmdColSize)
+	if len(name) >= l {
+		b.WriteString("\n  ")
+	} else {
+		l -= len(name)
+	}
+	for i := 0; i < l; i++ {
+		b.WriteByte(' ')
+	}
+	b.WriteString(desc)
+	return b.String()
+}
+
 func (c *command) FlagDescription() (out []string) {
 	for _, flg := range c.Flags() {
 		b := strings.Builder{}
@@ -123,14 +189,28 @@ func (c *command) FlagDescription() (out []string) {
 			b.WriteString(strings.Join(flg.def, " "))
 			b.WriteByte(')')
 		}
-		if flg.env != "" {
+		if len(flg.oneOf) > 0 {
+			b.WriteString(" (one of: ")
+			b.WriteString(strings.Join(flg.oneOf, ", "))
+			b.WriteByte(')')
+		}
+		if envs := flg.EnvNames(); len(envs) > 0 {
 			b.WriteString(" (env: ")
-			b.WriteString(flg.env)
+			b.WriteString(strings.Join(envs, ", "))
 			b.WriteByte(')')
 		}
 		if flg.required {
 			b.WriteString(" (required)")
 		}
+		for _, g := range flg.groups {
+			b.WriteString(g.Description(flg))
+		}
+		for _, cnd := range flg.requiredIf {
+			b.WriteString(" (required if " + cnd.String() + ")")
+		}
+		for _, cnd := range flg.excludes {
+			b.WriteString(" (not allowed if " + cnd.String() + ")")
+		}
 		out = append(out, b.String())
 	}
 	return
@@ -155,6 +235,16 @@ func (c *command) ArgumentDescription() (out []string) {
 			b.WriteString(strings.Join(arg.def, " "))
 			b.WriteByte(')')
 		}
+		if len(arg.oneOf) > 0 {
+			b.WriteString(" (one of: ")
+			b.WriteString(strings.Join(arg.oneOf, ", "))
+			b.WriteByte(')')
+		}
+		if envs := arg.EnvNames(); len(envs) > 0 {
+			b.WriteString(" (env: ")
+			b.WriteString(strings.Join(envs, ", "))
+			b.WriteByte(')')
+		}
 		if arg.required {
 			b.WriteString(" (required)")
 		}
@@ -180,9 +270,14 @@ func (c *command) CompleteFlags(val string) (out []string) {
 }
 
 func (c *command) CompleteSubcommands(val string) (out []string) {
-	for sc := range c.subcmdsMap {
-		if strings.HasPrefix(sc, val) {
-			out = append(out, sc+" ")
+	for _, sc := range c.subcmds {
+		if strings.HasPrefix(sc.Name, val) {
+			out = append(out, sc.Name+" ")
+		}
+	}
+	for _, name := range append(c.UserAliases(), c.Plugins()...) {
+		if strings.HasPrefix(name, val) {
+			out = append(out, name+" ")
 		}
 	}
 	return
@@ -203,13 +298,40 @@ func (c *command) Usage(w io.Writer) {
 	}
 }
 
+// ShortUsage writes only the usage line and how to get help
+func (c *command) ShortUsage(w io.Writer, helpFlag string) {
+	t := template.Must(template.New("").Parse(shortUsageTpl))
+	if err := t.Execute(w, struct {
+		tplContext
+		Path string
+		Help string
+	}{
+		tplContext: tplContext{
+			Cmd:   c,
+			Ident: computeIdent(c.opts.identSize),
+		},
+		Path: c.FullName(),
+		Help: helpFlag,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// FullName returns the names of the command chain separated by space
+func (c *command) FullName() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.FullName() + " " + c.Name
+}
+
 type tplContext struct {
 	Cmd   *command
 	Ident string
 }
 
 var parentCmdTpl = `Usage:
-{{.Ident}}{{.Cmd.Name}}{{range .Cmd.Flags}} {{.Usage}}{{end}} [command]
+{{.Ident}}{{.Cmd.Name}}{{range .Cmd.FlagsUsage}} {{.}}{{end}} [command]
 {{- if .Cmd.Description}}
 
 {{.Cmd.Description}}
@@ -219,6 +341,20 @@ Commands:
 {{- range .Cmd.SubcmdDescription}}
 {{$.Ident}}{{.}}
 {{- end}}
+{{- if .Cmd.UserAliasDescription}}
+
+Aliases:
+{{- range .Cmd.UserAliasDescription}}
+{{$.Ident}}{{.}}
+{{- end}}
+{{- end}}
+{{- if .Cmd.PluginDescription}}
+
+Plugins:
+{{- range .Cmd.PluginDescription}}
+{{$.Ident}}{{.}}
+{{- end}}
+{{- end}}
 {{- if .Cmd.FlagDescription}}
 
 Flags:
@@ -227,8 +363,13 @@ Flags:
 {{- end}}
 {{- end}}
 `
+var shortUsageTpl = `Usage:
+{{.Ident}}{{.Cmd.Name}}{{range .Cmd.FlagsUsage}} {{.}}{{end}}{{if .Cmd.HasSubcommands}} [command]{{else}}{{range .Cmd.Positionals}} {{.Usage}}{{end}}{{end}}
+
+Run '{{.Path}} {{.Help}}' for more information.
+`
 var leafCmdTpl = `Usage:
-{{.Ident}}{{.Cmd.Name}}{{range .Cmd.Flags}} {{.Usage}}{{end}}{{range .Cmd.Positionals}} {{.Usage}}{{end}}
+{{.Ident}}{{.Cmd.Name}}{{range .Cmd.FlagsUsage}} {{.}}{{end}}{{range .Cmd.Positionals}} {{.Usage}}{{end}}
 {{- if .Cmd.Description}}
 
 {{.Cmd.Description}}
@@ -53,6 +53,9 @@ func TestCompletion(t *testing.T) {
 		},
 	}
 
+	defer os.Unsetenv("COMP_LINE")
+	defer os.Unsetenv("COMP_POINT")
+
 	for _, c := range cases {
 		os.Setenv("COMP_LINE", c.Cmdline)
 		os.Setenv("COMP_POINT", c.Point)
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// condition on the value of a sibling flag
+type condition struct {
+	arg      *argument
+	value    string
+	hasValue bool
+}
+
+func parseCondition(c *command, s string) *condition {
+	name, val := s, ""
+	hasValue := false
+	if i := strings.Index(s, "="); i != -1 {
+		name, val = s[:i], s[i+1:]
+		hasValue = true
+	}
+	a := c.GetFlag("--" + name)
+	if a == nil {
+		panic(fmt.Sprintf("no such flag in condition for command %s: %s", c.Name, s))
+	}
+	return &condition{
+		arg:      a,
+		value:    val,
+		hasValue: hasValue,
+	}
+}
+
+// Holds reports whether the condition is met by the final value of the flag
+func (cnd *condition) Holds() bool {
+	a := cnd.arg
+	if !a.IsSet() {
+		return false
+	}
+	v := a.path.valueDeref()
+	if !cnd.hasValue {
+		if a.IsBool() {
+			return v.Bool()
+		}
+		return true
+	}
+	return strings.EqualFold(a.valueString(v), cnd.value)
+}
+
+func (cnd *condition) String() string {
+	if cnd.hasValue {
+		return cnd.arg.long + "=" + cnd.value
+	}
+	return cnd.arg.long + " is set"
+}
+
+// linkConditions resolves the requiredIf & excludes tags of the command and
+// its subcommands to the flags they reference
+func (c *command) linkConditions() {
+	for _, a := range c.Args() {
+		for _, s := range a.requiredIfTag {
+			a.requiredIf = append(a.requiredIf, parseCondition(c, s))
+		}
+		for _, s := range a.excludesTag {
+			a.excludes = append(a.excludes, parseCondition(c, s))
+		}
+	}
+	for _, sc := range c.subcmds {
+		sc.linkConditions()
+	}
+}
+
+// CheckConditions enforces the requiredIf & excludes conditions
+func (c *command) CheckConditions() (errs []error) {
+	for _, a := range c.Args() {
+		for _, cnd := range a.requiredIf {
+			if cnd.Holds() && !a.IsSet() {
+				errs = append(errs, usageErrorf("%s is required when %s", a.Name(), cnd))
+			}
+		}
+		for _, cnd := range a.excludes {
+			if cnd.Holds() && a.IsSet() && a.source != sourceDefault {
+				errs = append(errs, usageErrorf("%s cannot be used when %s", a.Name(), cnd))
+			}
+		}
+	}
+	return
+}
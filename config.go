@@ -0,0 +1,287 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ConfigFormat configuration file format
+type ConfigFormat uint32
+
+const (
+	// ConfigYAML YAML format
+	ConfigYAML ConfigFormat = iota
+	// ConfigTOML TOML format
+	ConfigTOML
+	// ConfigJSON JSON format. Help text is omitted as JSON has no comments
+	ConfigJSON
+)
+
+// ParseConfigFormat returns the format for the names yaml, yml, toml & json
+func ParseConfigFormat(s string) (ConfigFormat, error) {
+	switch strings.ToLower(s) {
+	case "yaml", "yml":
+		return ConfigYAML, nil
+	case "toml":
+		return ConfigTOML, nil
+	case "json":
+		return ConfigJSON, nil
+	}
+	return 0, fmt.Errorf("unknown config format: %s", s)
+}
+
+// WriteConfigTemplate writes a configuration template for the command in cmdPath
+// on the default CLI
+func WriteConfigTemplate(cmdPath string, format ConfigFormat, w io.Writer) error {
+	return defaultCLI.WriteConfigTemplate(cmdPath, format, w)
+}
+
+// WriteConfigTemplate writes a configuration template for the command in cmdPath.
+// cmdPath is the space separated chain of command names starting from the root.
+// Flags of the command and its subcommands are written with their default values
+// and their help text as comments. Optional flags without default are commented out
+// in YAML and TOML, required ones are written with their zero value as placeholder
+func (cli *CLI) WriteConfigTemplate(cmdPath string, format ConfigFormat, w io.Writer) error {
+	c, err := cli.lookupCommand(strings.Fields(cmdPath))
+	if err != nil {
+		return err
+	}
+	b := &bytes.Buffer{}
+	switch format {
+	case ConfigYAML:
+		writeConfigYAML(b, c, "")
+	case ConfigTOML:
+		writeConfigTOML(b, c, "")
+	case ConfigJSON:
+		writeConfigJSON(b, c, "")
+		b.WriteByte('\n')
+	default:
+		return fmt.Errorf("unknown config format: %d", format)
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+func (cli *CLI) lookupCommand(names []string) (*command, error) {
+	if len(names) == 0 {
+		return nil, ErrCommandNotFound{}
+	}
+	c, err := cli.findRootCommand(names[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range names[1:] {
+		sc, ok := c.LookupSubcommand(n)
+		if !ok {
+			return nil, ErrCommandNotFound{Command: n}
+		}
+		c = sc
+	}
+	return c, nil
+}
+
+// configSubcommands returns the subcommands that have something to configure
+func configSubcommands(c *command) (out []*command) {
+	for _, sc := range c.subcmds {
+		if sc.path.Type() == reflect.TypeOf(&ConfigCmd{}) {
+			continue
+		}
+		if hasConfig(sc) {
+			out = append(out, sc)
+		}
+	}
+	return
+}
+
+func hasConfig(c *command) bool {
+	return len(c.Flags()) > 0 || len(configSubcommands(c)) > 0
+}
+
+func configKey(a *argument) string {
+	return strings.TrimPrefix(a.long, "--")
+}
+
+func configComment(a *argument) string {
+	b := strings.Builder{}
+	b.WriteString(a.help)
+	if envs := a.EnvNames(); len(envs) > 0 {
+		b.WriteString(" (env: ")
+		b.WriteString(strings.Join(envs, ", "))
+		b.WriteByte(')')
+	}
+	if a.required {
+		b.WriteString(" (required)")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// configValue returns the default value or the zero value as literal
+func configValue(a *argument) string {
+	t := a.typ
+	if isPtr(t) {
+		t = t.Elem()
+	}
+	if a.isSlice {
+		vals := []string{}
+		for _, d := range a.def {
+			vals = append(vals, configScalar(t.Elem(), a.enum, d))
+		}
+		return "[" + strings.Join(vals, ", ") + "]"
+	}
+	if a.def != nil {
+		return configScalar(t, a.enum, a.def[0])
+	}
+	switch {
+	case a.enum != nil:
+		return quoteString(a.enum.Name(reflect.Zero(t).Interface()))
+	case isBool(t):
+		return "false"
+	case isNumber(t):
+		return "0"
+	}
+	return `""`
+}
+
+func configScalar(t reflect.Type, enm *enum, s string) string {
+	if enm == nil && (isBool(t) || isNumber(t)) {
+		return s
+	}
+	return quoteString(s)
+}
+
+func quoteString(s string) string {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		panic(err)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeConfigComment(w *bytes.Buffer, ident, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, l := range strings.Split(comment, "\n") {
+		fmt.Fprintf(w, "%s# %s\n", ident, l)
+	}
+}
+
+func writeConfigYAML(w *bytes.Buffer, c *command, ident string) {
+	for i, a := range c.Flags() {
+		if i > 0 {
+			w.WriteByte('\n')
+		}
+		writeConfigComment(w, ident, configComment(a))
+		commented := ""
+		if a.def == nil && !a.required {
+			commented = "# "
+		}
+		fmt.Fprintf(w, "%s%s%s: %s\n", ident, commented, configKey(a), configValue(a))
+	}
+	for i, sc := range configSubcommands(c) {
+		if i > 0 || len(c.Flags()) > 0 {
+			w.WriteByte('\n')
+		}
+		writeConfigComment(w, ident, sc.help)
+		fmt.Fprintf(w, "%s%s:\n", ident, sc.Name)
+		writeConfigYAML(w, sc, ident+"  ")
+	}
+}
+
+func writeConfigTOML(w *bytes.Buffer, c *command, table string) {
+	for i, a := range c.Flags() {
+		if i > 0 {
+			w.WriteByte('\n')
+		}
+		writeConfigComment(w, "", configComment(a))
+		commented := ""
+		if a.def == nil && !a.required {
+			commented = "# "
+		}
+		fmt.Fprintf(w, "%s%s = %s\n", commented, tomlKey(configKey(a)), configValue(a))
+	}
+	for i, sc := range configSubcommands(c) {
+		if i > 0 || len(c.Flags()) > 0 {
+			w.WriteByte('\n')
+		}
+		name := tomlKey(sc.Name)
+		if table != "" {
+			name = table + "." + name
+		}
+		writeConfigComment(w, "", sc.help)
+		fmt.Fprintf(w, "[%s]\n", name)
+		writeConfigTOML(w, sc, name)
+	}
+}
+
+// tomlKey quotes key unless it is a bare key, keeping keys like db.host flat
+// as in yaml & json instead of dotted
+func tomlKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return quoteString(key)
+		}
+	}
+	return key
+}
+
+func writeConfigJSON(w *bytes.Buffer, c *command, ident string) {
+	w.WriteString("{")
+	sep := "\n"
+	for _, a := range c.Flags() {
+		fmt.Fprintf(w, "%s%s  %s: %s", sep, ident, quoteString(configKey(a)), configValue(a))
+		sep = ",\n"
+	}
+	for _, sc := range configSubcommands(c) {
+		fmt.Fprintf(w, "%s%s  %s: ", sep, ident, quoteString(sc.Name))
+		writeConfigJSON(w, sc, ident+"  ")
+		sep = ",\n"
+	}
+	if sep != "\n" {
+		w.WriteString("\n" + ident)
+	}
+	w.WriteString("}")
+}
+
+// ConfigCmd is a ready made `config` command. Add it as a subcommand field of the
+// root command to provide `config init`
+type ConfigCmd struct {
+	Init *ConfigInitCmd `usage:"write a configuration file template"`
+}
+
+// ConfigInitCmd writes a configuration template of the root command
+type ConfigInitCmd struct {
+	Format string `short:"f" default:"yaml" usage:"configuration format: yaml, toml or json"`
+	Output string `short:"o" usage:"output file, stdout if not set"`
+}
+
+// Run writes the configuration template
+func (c *ConfigInitCmd) Run(ctx context.Context) error {
+	cli := cliFromContext(ctx)
+	if cli == nil || len(cli.cmdList) == 0 {
+		return errors.New("config init: not executed by a CLI")
+	}
+	format, err := ParseConfigFormat(c.Format)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = cli.stdout
+	if c.Output != "" {
+		f, err := os.OpenFile(c.Output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return cli.WriteConfigTemplate(cli.cmdList[0].Name, format, w)
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+type configTestRoot struct {
+	Host    string   `default:"localhost" usage:"server host"`
+	Port    int      `default:"8080" env:"PORT" usage:"server port"`
+	Token   string   `cli:"required"`
+	Tags    []string `default:"a b"`
+	Verbose bool
+	DB      struct {
+		Host string `default:"db" usage:"database host"`
+	}
+	Deploy *struct {
+		Target string `usage:"deploy target"`
+	} `usage:"deploy the app"`
+	Config *ConfigCmd
+}
+
+func TestWriteConfigTemplate(t *testing.T) {
+	c := NewCLI()
+	c.NewCommand("app", &configTestRoot{})
+
+	cases := []struct {
+		Name   string
+		Format ConfigFormat
+		Expect string
+	}{
+		{
+			"yaml",
+			ConfigYAML,
+			`# server host (env: HOST)
+host: "localhost"
+
+# server port (env: PORT)
+port: 8080
+
+# (env: TOKEN) (required)
+token: ""
+
+# (env: TAGS)
+tags: ["a", "b"]
+
+# (env: VERBOSE)
+# verbose: false
+
+# database host (env: DB_HOST)
+db.host: "db"
+
+# deploy the app
+deploy:
+  # deploy target (env: TARGET)
+  # target: ""
+`,
+		},
+		{
+			"toml",
+			ConfigTOML,
+			`# server host (env: HOST)
+host = "localhost"
+
+# server port (env: PORT)
+port = 8080
+
+# (env: TOKEN) (required)
+token = ""
+
+# (env: TAGS)
+tags = ["a", "b"]
+
+# (env: VERBOSE)
+# verbose = false
+
+# database host (env: DB_HOST)
+"db.host" = "db"
+
+# deploy the app
+[deploy]
+# deploy target (env: TARGET)
+# target = ""
+`,
+		},
+		{
+			"json",
+			ConfigJSON,
+			`{
+  "host": "localhost",
+  "port": 8080,
+  "token": "",
+  "tags": ["a", "b"],
+  "verbose": false,
+  "db.host": "db",
+  "deploy": {
+    "target": ""
+  }
+}
+`,
+		},
+	}
+
+	for _, cs := range cases {
+		t.Run(cs.Name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := c.WriteConfigTemplate("app", cs.Format, buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != cs.Expect {
+				t.Fatalf("wrong template:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestConfigInitCmd(t *testing.T) {
+	c := NewCLI()
+	c.NewCommand("app", &configTestRoot{})
+	buf := &bytes.Buffer{}
+	c.stdout = buf
+
+	if err := c.Parse([]string{"app", "--token", "x", "config", "init", "-f", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 || buf.Bytes()[0] != '{' {
+		t.Fatalf("should have written json template: %s", buf.String())
+	}
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"golang.org/x/exp/constraints"
@@ -56,6 +57,16 @@ func (e *enum) Name(v interface{}) string {
 	return e.names[v]
 }
 
+// Names returns the enum names sorted
+func (e *enum) Names() []string {
+	out := make([]string, 0, len(e.values))
+	for n := range e.values {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
 func (e *enum) Value(s string) interface{} {
 	return e.values[strings.ToUpper(s)]
 }
@@ -1,22 +1,252 @@
 package cli
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var ErrInvalidFlag = func(flg string) error { return fmt.Errorf("invalid flag: %s", flg) }
-var ErrInvalidValue = func(val, flg string) error { return fmt.Errorf("invalid value: %s for flag: %s", val, flg) }
 
+// ErrCommandNotFound is returned for unknown commands. Suggestions holds
+// the commands with similar names
 type ErrCommandNotFound struct {
-	Command string
+	Command     string
+	Suggestions []string
 }
 
 func (e ErrCommandNotFound) Error() string {
-	return fmt.Sprintf("command not found: %s", e.Command)
+	return fmt.Sprintf("command not found: %s", e.Command) + didYouMean(e.Suggestions)
 }
 
+func (e ErrCommandNotFound) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrAmbiguousCommand is returned when a prefix matches more than one
+// subcommand
+type ErrAmbiguousCommand struct {
+	Command    string
+	Candidates []string
+}
+
+func (e ErrAmbiguousCommand) Error() string {
+	return fmt.Sprintf("ambiguous command: %s, could be one of %s", e.Command, strings.Join(e.Candidates, ", "))
+}
+
+func (e ErrAmbiguousCommand) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrNoSuchFlag is returned for unknown flags. Suggestions holds the flags
+// with similar names
 type ErrNoSuchFlag struct {
-	Flag string
+	Flag        string
+	Suggestions []string
 }
 
 func (e ErrNoSuchFlag) Error() string {
-	return fmt.Sprintf("no such flag: %s", e.Flag)
+	return fmt.Sprintf("no such flag: %s", e.Flag) + didYouMean(e.Suggestions)
+}
+
+func (e ErrNoSuchFlag) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrAmbiguousFlag is returned when an abbreviated flag matches more than one
+// flag
+type ErrAmbiguousFlag struct {
+	Flag       string
+	Candidates []string
+}
+
+func (e ErrAmbiguousFlag) Error() string {
+	return fmt.Sprintf("ambiguous flag: %s, could be one of %s", e.Flag, strings.Join(e.Candidates, ", "))
+}
+
+func (e ErrAmbiguousFlag) ExitCode() int {
+	return ExitUsage
+}
+
+func didYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(", did you mean %s?", suggestions[0])
+	}
+	return fmt.Sprintf(", did you mean one of %s?", strings.Join(suggestions, ", "))
+}
+
+// ErrMissingValue is returned when a flag expecting a value is followed
+// by another flag or is the last argument
+type ErrMissingValue struct {
+	Flag  string
+	Index int
+}
+
+func (e ErrMissingValue) Error() string {
+	return fmt.Sprintf("missing value for flag: %s", e.Flag)
+}
+
+func (e ErrMissingValue) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrRequired is returned when a required flag or argument is not set
+// from any source
+type ErrRequired struct {
+	Name       string
+	Env        []string
+	Positional bool
+}
+
+func (e ErrRequired) Error() string {
+	kind := "flag"
+	if e.Positional {
+		kind = "argument"
+	}
+	msg := fmt.Sprintf("required %s not set: %s", kind, e.Name)
+	if len(e.Env) > 0 {
+		msg += fmt.Sprintf(" (env: %s)", strings.Join(e.Env, ", "))
+	}
+	return msg
+}
+
+func (e ErrRequired) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrInvalidValue is returned when a value cannot be set or fails validation.
+// Source is where the value came from
+type ErrInvalidValue struct {
+	Flag   string
+	Value  string
+	Source string
+	Cause  error
+	// Secret is set for secret arguments, Value is left empty
+	Secret bool
+}
+
+func (e ErrInvalidValue) Error() string {
+	msg := fmt.Sprintf("invalid value: %s for %s", e.Value, e.Flag)
+	if e.Secret {
+		msg = "invalid secret value for " + e.Flag
+	}
+	if e.Source != "" {
+		msg += fmt.Sprintf(" (from %s)", e.Source)
+	}
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e ErrInvalidValue) ExitCode() int {
+	return ExitUsage
+}
+
+func (e ErrInvalidValue) Unwrap() error {
+	return e.Cause
+}
+
+// ErrTooManyPositionals is returned when a value is found and all positional
+// arguments are set. Index is the position in args
+type ErrTooManyPositionals struct {
+	Value string
+	Index int
+}
+
+func (e ErrTooManyPositionals) Error() string {
+	return fmt.Sprintf("too many positional arguments: %s", e.Value)
+}
+
+func (e ErrTooManyPositionals) ExitCode() int {
+	return ExitUsage
+}
+
+// ErrUnexpectedToken is returned when an argument is not expected at its
+// position. Index is the position in args
+type ErrUnexpectedToken struct {
+	Token string
+	Index int
+}
+
+func (e ErrUnexpectedToken) Error() string {
+	return fmt.Sprintf("unexpected argument %d: %s", e.Index, e.Token)
+}
+
+func (e ErrUnexpectedToken) ExitCode() int {
+	return ExitUsage
+}
+
+// ParseErrors holds all the errors found when errors are collected
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ParseErrors) ExitCode() int {
+	return ExitUsage
+}
+
+func (e ParseErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target. errors.Is follows
+// Unwrap() []error only since go 1.20
+func (e ParseErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target. errors.As follows
+// Unwrap() []error only since go 1.20
+func (e ParseErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedError hides the secret value val in the message of err
+type redactedError struct {
+	err error
+	val string
+}
+
+func (e redactedError) Error() string {
+	if e.val == "" {
+		return e.err.Error()
+	}
+	return strings.ReplaceAll(e.err.Error(), e.val, "***")
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// usageError is an error in the usage of the command line
+type usageError struct {
+	error
+}
+
+func (e usageError) ExitCode() int {
+	return ExitUsage
+}
+
+func usageErrorf(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// ExitOK successful execution
+	ExitOK = 0
+	// ExitError generic runtime error
+	ExitError = 1
+	// ExitUsage invalid command line
+	ExitUsage = 2
+	// ExitCanceled execution was canceled
+	ExitCanceled = 130
+)
+
+// ExitCoder is implemented by errors that carry the exit code of the process
+type ExitCoder interface {
+	ExitCode() int
+}
+
+type exitError struct {
+	err  error
+	code int
+}
+
+func (e exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+func (e exitError) ExitCode() int {
+	return e.code
+}
+
+// Exit wraps err so that the process exits with code
+func Exit(err error, code int) error {
+	return exitError{err: err, code: code}
+}
+
+// ErrorSelection selects the error returned by Run when more than one
+// error occurs, depending on the OnErrorStrategy
+type ErrorSelection uint
+
+const (
+	// ErrorLast return the last error
+	ErrorLast ErrorSelection = iota
+	// ErrorFirst return the first error
+	ErrorFirst
+)
+
+func (cli *CLI) selectError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if cli.options.errorSelection == ErrorFirst {
+		return errs[0]
+	}
+	return errs[len(errs)-1]
+}
+
+// ExitCode returns the exit code for err on the default CLI
+func ExitCode(err error) int {
+	return defaultCLI.ExitCode(err)
+}
+
+// ExitCode returns the exit code for err. The exit code mapper is tried
+// first, then ExitCoder. Canceled contexts exit with ExitCanceled and
+// any other error with ExitError
+func (cli *CLI) ExitCode(err error) int {
+	return cli.exitCode(err, ExitError)
+}
+
+// exitCode returns the exit code for err like ExitCode, with def for errors
+// without a code
+func (cli *CLI) exitCode(err error, def int) int {
+	if err == nil {
+		return ExitOK
+	}
+	if cli.options.exitCodeMapper != nil {
+		if code := cli.options.exitCodeMapper(err); code != ExitOK {
+			return code
+		}
+	}
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitCanceled
+	}
+	return def
+}
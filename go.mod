@@ -8,4 +8,4 @@ require (
 	github.com/scylladb/go-set v1.0.2
 )
 
-require golang.org/x/exp v0.0.0-20220827204233-334a2380cb91 // indirect
+require golang.org/x/exp v0.0.0-20220827204233-334a2380cb91
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+type groupKind uint8
+
+const (
+	// groupXor at most one flag of the group can be set
+	groupXor groupKind = iota
+	// groupAnd all or none of the flags of the group must be set
+	groupAnd
+	// groupOneRequired at least one flag of the group must be set
+	groupOneRequired
+)
+
+type flagGroup struct {
+	kind groupKind
+	name string
+	args []*argument
+}
+
+// AddToGroup adds a to the group of kind with name, creating the group if needed
+func (c *command) AddToGroup(kind groupKind, name string, a *argument) {
+	var g *flagGroup
+	for _, grp := range c.groups {
+		if grp.kind == kind && grp.name == name {
+			g = grp
+			break
+		}
+	}
+	if g == nil {
+		g = &flagGroup{kind: kind, name: name}
+		c.groups = append(c.groups, g)
+	}
+	g.args = append(g.args, a)
+	a.groups = append(a.groups, g)
+}
+
+// CheckGroups enforces the flag groups of the command
+func (c *command) CheckGroups() (errs []error) {
+	for _, g := range c.groups {
+		if err := g.Check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// Check returns an error if the group constraint is not met. Values set from
+// defaults are not taken into account
+func (g *flagGroup) Check() error {
+	set, unset := []string{}, []string{}
+	for _, a := range g.args {
+		if a.IsSet() && a.source != sourceDefault {
+			set = append(set, a.long)
+		} else {
+			unset = append(unset, a.long)
+		}
+	}
+	switch g.kind {
+	case groupXor:
+		if len(set) > 1 {
+			return usageErrorf("flags %s are mutually exclusive", strings.Join(set, ", "))
+		}
+	case groupAnd:
+		if len(set) > 0 && len(unset) > 0 {
+			return usageErrorf("flags %s must be set together: %s not set", g.flagNames(", "), strings.Join(unset, ", "))
+		}
+	case groupOneRequired:
+		if len(set) == 0 {
+			return usageErrorf("one of the flags %s is required", g.flagNames(", "))
+		}
+	}
+	return nil
+}
+
+func (g *flagGroup) flagNames(sep string) string {
+	names := []string{}
+	for _, a := range g.args {
+		names = append(names, a.long)
+	}
+	return strings.Join(names, sep)
+}
+
+func (g *flagGroup) others(a *argument) []string {
+	names := []string{}
+	for _, o := range g.args {
+		if o != a {
+			names = append(names, o.long)
+		}
+	}
+	return names
+}
+
+// Description returns the help annotation of the group for flag a
+func (g *flagGroup) Description(a *argument) string {
+	switch g.kind {
+	case groupXor:
+		return fmt.Sprintf(" (conflicts with: %s)", strings.Join(g.others(a), ", "))
+	case groupAnd:
+		return fmt.Sprintf(" (requires: %s)", strings.Join(g.others(a), ", "))
+	case groupOneRequired:
+		return fmt.Sprintf(" (required: one of %s)", g.flagNames(", "))
+	}
+	return ""
+}
+
+// FlagsUsage returns the usage of the flags, rendering the members of
+// the flag groups together
+func (c *command) FlagsUsage() (out []string) {
+	done := map[*argument]bool{}
+	for _, a := range c.Flags() {
+		if done[a] {
+			continue
+		}
+		g := a.usageGroup()
+		if g == nil {
+			out = append(out, a.Usage())
+			continue
+		}
+		optional := true
+		members := []*argument{}
+		for _, m := range g.args {
+			if !done[m] && m.usageGroup() == g {
+				members = append(members, m)
+				done[m] = true
+			}
+		}
+		sep := " | "
+		for _, og := range members[0].groups {
+			if og.kind == groupOneRequired {
+				optional = false
+			}
+		}
+		if g.kind == groupAnd {
+			sep = " "
+		}
+		usages := []string{}
+		for _, m := range members {
+			if m.required {
+				optional = false
+			}
+			usages = append(usages, m.usage(false))
+		}
+		if len(usages) == 1 {
+			out = append(out, members[0].usage(optional))
+			continue
+		}
+		if optional {
+			out = append(out, "["+strings.Join(usages, sep)+"]")
+		} else {
+			out = append(out, "("+strings.Join(usages, sep)+")")
+		}
+	}
+	return
+}
+
+// usageGroup returns the group the flag is rendered with in usage. A flag
+// belongs to many groups but is rendered only in the first
+func (a *argument) usageGroup() *flagGroup {
+	if len(a.groups) == 0 {
+		return nil
+	}
+	return a.groups[0]
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	cleanupType = reflect.TypeOf(func() {})
+)
+
+// Provide registers a provider in default CLI
+func Provide(fn interface{}) {
+	defaultCLI.Provide(fn)
+}
+
+// Provide registers fn as the provider of the type of its first result. fn
+// is one of func(...) T, func(...) (T, error) or func(...) (T, func(), error),
+// the func() being a cleanup called once the command chain is executed. The
+// arguments of fn are the command structs of the chain, the context or types
+// of other providers. Panics if fn is not a valid provider
+func (cli *CLI) Provide(fn interface{}) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || !isProvider(v.Type()) {
+		panic(fmt.Sprintf("invalid provider: %T", fn))
+	}
+	cli.providers[v.Type().Out(0)] = v
+}
+
+func isProvider(t reflect.Type) bool {
+	if t.IsVariadic() {
+		return false
+	}
+	switch t.NumOut() {
+	case 1:
+		return true
+	case 2:
+		return t.Out(1) == errorType
+	case 3:
+		return t.Out(1) == cleanupType && t.Out(2) == errorType
+	}
+	return false
+}
+
+// injector resolves the dependencies of the command chain. Each provider is
+// called at most once per execution
+type injector struct {
+	providers map[reflect.Type]reflect.Value
+	values    map[reflect.Type]reflect.Value
+	resolving map[reflect.Type]bool
+	cleanups  []func()
+}
+
+func (cli *CLI) newInjector() *injector {
+	in := &injector{
+		providers: cli.providers,
+		values:    map[reflect.Type]reflect.Value{},
+		resolving: map[reflect.Type]bool{},
+	}
+	for _, inf := range cli.runList {
+		in.values[reflect.TypeOf(inf)] = reflect.ValueOf(inf)
+	}
+	return in
+}
+
+// Resolve returns the value of type t, calling its provider if needed
+func (in *injector) Resolve(ctx context.Context, t reflect.Type) (reflect.Value, error) {
+	if t == contextType {
+		return reflect.ValueOf(&ctx).Elem(), nil
+	}
+	if v, ok := in.values[t]; ok {
+		return v, nil
+	}
+	p, ok := in.providers[t]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no provider for type: %s", t)
+	}
+	if in.resolving[t] {
+		return reflect.Value{}, fmt.Errorf("dependency cycle on type: %s", t)
+	}
+	in.resolving[t] = true
+	defer delete(in.resolving, t)
+
+	args, err := in.args(ctx, p.Type())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	out := p.Call(args)
+	if n := len(out); n > 1 && !out[n-1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("provide %s: %w", t, out[n-1].Interface().(error))
+	}
+	if len(out) == 3 && !out[1].IsNil() {
+		in.cleanups = append(in.cleanups, out[1].Interface().(func()))
+	}
+	in.values[t] = out[0]
+	return out[0], nil
+}
+
+// args resolves the arguments of the func type t
+func (in *injector) args(ctx context.Context, t reflect.Type) ([]reflect.Value, error) {
+	args := make([]reflect.Value, 0, t.NumIn())
+	for i := 0; i < t.NumIn(); i++ {
+		v, err := in.Resolve(ctx, t.In(i))
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+// Inject sets the fields of c tagged `inject:""`
+func (in *injector) Inject(ctx context.Context, c *command) error {
+	for _, p := range c.injectFlds {
+		v, err := in.Resolve(ctx, p.Type())
+		if err != nil {
+			return fmt.Errorf("inject %s in command %s: %w", p.path[len(p.path)-1], c.Name, err)
+		}
+		p.value().Set(v)
+	}
+	return nil
+}
+
+// RunFunc returns the Run method of inf taking dependencies after the
+// context, nil if it has none
+func (in *injector) RunFunc(inf interface{}) RunFunc {
+	m := reflect.ValueOf(inf).MethodByName("Run")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.IsVariadic() || t.NumIn() == 0 || t.In(0) != contextType ||
+		t.NumOut() != 1 || t.Out(0) != errorType {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		args, err := in.args(ctx, t)
+		if err != nil {
+			return err
+		}
+		err, _ = m.Call(args)[0].Interface().(error)
+		return err
+	}
+}
+
+// Cleanup calls the cleanups of the providers in reverse order
+func (in *injector) Cleanup() {
+	for i := len(in.cleanups) - 1; i >= 0; i-- {
+		in.cleanups[i]()
+	}
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines from a terminal in raw mode, with history & tab
+// completion. Keys follow the emacs bindings of readline
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	prompt   string
+	history  []string
+	complete func(line string) []string
+
+	buf   []rune
+	pos   int
+	hist  int
+	saved []rune
+}
+
+// ReadLine reads a line. Returns io.EOF on Ctrl-D on an empty line and
+// errInterrupted on Ctrl-C
+func (e *lineEditor) ReadLine() (string, error) {
+	e.buf, e.pos = nil, 0
+	e.hist = len(e.history)
+	e.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			line := string(e.buf)
+			if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos)
+		case 127, 8: // Backspace
+			if e.pos > 0 {
+				e.pos--
+				e.delete(e.pos)
+			}
+		case 1: // Ctrl-A
+			e.pos = 0
+		case 5: // Ctrl-E
+			e.pos = len(e.buf)
+		case 2: // Ctrl-B
+			e.move(-1)
+		case 6: // Ctrl-F
+			e.move(1)
+		case 11: // Ctrl-K
+			e.buf = e.buf[:e.pos]
+		case 21: // Ctrl-U
+			e.buf = append([]rune{}, e.buf[e.pos:]...)
+			e.pos = 0
+		case 16: // Ctrl-P
+			e.historyMove(-1)
+		case 14: // Ctrl-N
+			e.historyMove(1)
+		case '\t':
+			e.tab()
+		case 27:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+		e.refresh()
+	}
+}
+
+// escape handles the escape sequences of the arrows, home, end & delete keys
+func (e *lineEditor) escape() {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case 'A':
+		e.historyMove(-1)
+	case 'B':
+		e.historyMove(1)
+	case 'C':
+		e.move(1)
+	case 'D':
+		e.move(-1)
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.buf)
+	case '3':
+		if r, _, _ := e.in.ReadRune(); r == '~' {
+			e.delete(e.pos)
+		}
+	}
+}
+
+func (e *lineEditor) insert(rs []rune) {
+	buf := append([]rune{}, e.buf[:e.pos]...)
+	buf = append(buf, rs...)
+	e.buf = append(buf, e.buf[e.pos:]...)
+	e.pos += len(rs)
+}
+
+func (e *lineEditor) delete(i int) {
+	if i < len(e.buf) {
+		e.buf = append(e.buf[:i], e.buf[i+1:]...)
+	}
+}
+
+func (e *lineEditor) move(d int) {
+	if p := e.pos + d; p >= 0 && p <= len(e.buf) {
+		e.pos = p
+	}
+}
+
+// historyMove moves d entries in history. The line being edited is kept to be
+// restored after the last entry
+func (e *lineEditor) historyMove(d int) {
+	i := e.hist + d
+	if i < 0 || i > len(e.history) {
+		return
+	}
+	if e.hist == len(e.history) {
+		e.saved = e.buf
+	}
+	e.hist = i
+	if i == len(e.history) {
+		e.buf = e.saved
+	} else {
+		e.buf = []rune(e.history[i])
+	}
+	e.pos = len(e.buf)
+}
+
+// tab completes the word before the cursor. A unique candidate replaces the
+// word, many candidates are listed unless they share a longer prefix
+func (e *lineEditor) tab() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && !unicode.IsSpace(e.buf[start-1]) && e.buf[start-1] != '=' {
+		start--
+	}
+	cands := e.complete(string(e.buf[:e.pos]))
+	if len(cands) == 0 {
+		return
+	}
+	word := string(e.buf[start:e.pos])
+	repl := commonPrefix(cands)
+	if len(cands) == 1 {
+		repl = cands[0]
+	}
+	if len(repl) > len(word) {
+		e.buf = append(e.buf[:start:start], e.buf[e.pos:]...)
+		e.pos = start
+		e.insert([]rune(repl))
+		return
+	}
+	names := make([]string, 0, len(cands))
+	for _, c := range cands {
+		names = append(names, strings.TrimSpace(c))
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(names, "  "))
+}
+
+func (e *lineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.buf))
+	if n := len(e.buf) - e.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+func commonPrefix(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	pfx := s[0]
+	for _, v := range s[1:] {
+		for !strings.HasPrefix(v, pfx) {
+			pfx = pfx[:len(pfx)-1]
+		}
+	}
+	return pfx
+}
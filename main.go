@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+)
+
+// Main creates a new CLI with options, parses os.Args for cmd and runs the
+// command chain. On error it exits the process. See (*CLI).Main
+func Main(ctx context.Context, cmd interface{}, options ...Option) {
+	NewCLI(options...).Main(ctx, cmd)
+}
+
+// Main parses os.Args for cmd and runs the command chain. Errors are written
+// to the error output. On parse errors the short usage of the command is
+// written as well. The exit code is computed by ExitCode, parse errors
+// without a code exit with ExitUsage
+func (cli *CLI) Main(ctx context.Context, cmd interface{}) {
+	if err := cli.ParseCommand(cmd); err != nil {
+		fmt.Fprintln(cli.errorOut, "error:", err)
+		if cli.errCmd != nil {
+			fmt.Fprintln(cli.errorOut)
+			cli.errCmd.ShortUsage(cli.errorOut, cli.options.helpLong)
+		}
+		cli.osExit(cli.exitCode(err, ExitUsage))
+		return
+	}
+	if err := cli.Run(ctx); err != nil {
+		fmt.Fprintln(cli.errorOut, "error:", err)
+		cli.osExit(cli.ExitCode(err))
+	}
+}
@@ -1,5 +1,10 @@
 package cli
 
+import (
+	"os"
+	"syscall"
+)
+
 type Separator byte
 
 const (
@@ -8,22 +13,36 @@ const (
 )
 
 type cliOptions struct {
-	tags           StructTags
-	globalsEnabled bool
-	argCase        Case
-	envCase        Case
-	cmdCase        Case
-	argSplicer     Splicer
-	envSplicer     Splicer
-	helpLong       string
-	helpShort      string
-	versionLong    string
-	versionShort   string
-	strategy       OnErrorStrategy
-	separator      Separator
-	cmdColSize     uint
-	flagColSize    uint
-	identSize      uint
+	tags            StructTags
+	globalsEnabled  bool
+	argCase         Case
+	envCase         Case
+	cmdCase         Case
+	argSplicer      Splicer
+	envSplicer      Splicer
+	helpLong        string
+	helpShort       string
+	versionLong     string
+	versionShort    string
+	strategy        OnErrorStrategy
+	collectErrors   bool
+	errorSelection  ErrorSelection
+	exitCodeMapper  func(error) int
+	signals         []os.Signal
+	panicRecovery   bool
+	crashHandler    func(ErrPanic)
+	middleware      []Middleware
+	pluginPrefix    string
+	prefixMatching  bool
+	abbreviations   bool
+	aliases         map[string]string
+	responsePrefix  rune
+	noPrompt        bool
+	suggestDistance uint
+	separator       Separator
+	cmdColSize      uint
+	flagColSize     uint
+	identSize       uint
 }
 
 // Option option type for Parser
@@ -71,6 +90,127 @@ func WithOnErrorStrategy(str OnErrorStrategy) Option {
 	}
 }
 
+// WithCollectErrors makes Parse check all the values and return all the
+// errors found as ParseErrors instead of stopping at the first one
+func WithCollectErrors() Option {
+	return func(o *cliOptions) {
+		o.collectErrors = true
+	}
+}
+
+// WithSuggestionDistance sets the max edit distance of the suggestions for unknown
+// flags & commands. Default is 2, 0 disables suggestions
+func WithSuggestionDistance(d uint) Option {
+	return func(o *cliOptions) {
+		o.suggestDistance = d
+	}
+}
+
+// WithErrorSelection selects which error Run returns when execution
+// continues on errors. Default is ErrorLast
+func WithErrorSelection(s ErrorSelection) Option {
+	return func(o *cliOptions) {
+		o.errorSelection = s
+	}
+}
+
+// WithExitCodeMapper sets a func mapping errors to exit codes. Returning 0
+// falls back to the default mapping
+func WithExitCodeMapper(f func(error) int) Option {
+	return func(o *cliOptions) {
+		o.exitCodeMapper = f
+	}
+}
+
+// WithSignalHandling cancels the context passed to the runners when one of
+// signals is received. Default signals are SIGINT & SIGTERM. A second signal
+// exits immediately. Persistent post runners are executed for cleanup and Run
+// returns ErrSignal. The signal can be retrieved by SignalFromContext
+func WithSignalHandling(signals ...os.Signal) Option {
+	return func(o *cliOptions) {
+		if len(signals) == 0 {
+			signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+		}
+		o.signals = signals
+	}
+}
+
+// WithPanicRecovery recovers panics of the runners and converts them to ErrPanic.
+// Pending post runners are executed according to the OnErrorStrategy
+func WithPanicRecovery() Option {
+	return func(o *cliOptions) {
+		o.panicRecovery = true
+	}
+}
+
+// WithCrashHandler sets a func called with the recovered panic, e.g. to write
+// a crash report. It enables panic recovery
+func WithCrashHandler(h func(ErrPanic)) Option {
+	return func(o *cliOptions) {
+		o.panicRecovery = true
+		o.crashHandler = h
+	}
+}
+
+// WithMiddleware adds middleware wrapping the execution of the command chain.
+// The first middleware is the outermost
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *cliOptions) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithPrefixMatching accepts unique prefixes of the names & aliases of
+// subcommands
+func WithPrefixMatching() Option {
+	return func(o *cliOptions) {
+		o.prefixMatching = true
+	}
+}
+
+// WithFlagAbbreviation accepts unique prefixes of the long flag names.
+// Exact matches take precedence
+func WithFlagAbbreviation() Option {
+	return func(o *cliOptions) {
+		o.abbreviations = true
+	}
+}
+
+// WithAliases adds user aliases expanding the subcommand of the root command
+// they name, e.g. "st": "status --short". Subcommands take precedence
+func WithAliases(aliases map[string]string) Option {
+	return func(o *cliOptions) {
+		for name, exp := range aliases {
+			o.aliases[name] = exp
+		}
+	}
+}
+
+// WithResponseFiles replaces args starting with prefix, e.g. '@' in @args.txt,
+// by the shell quoted words of the file they name. Response files can
+// include other response files
+func WithResponseFiles(prefix rune) Option {
+	return func(o *cliOptions) {
+		o.responsePrefix = prefix
+	}
+}
+
+// WithoutPrompting disables prompting for the missing required values when
+// stdin is a terminal, e.g. for CI
+func WithoutPrompting() Option {
+	return func(o *cliOptions) {
+		o.noPrompt = true
+	}
+}
+
+// WithExternalPlugins runs executables named prefix+name found in PATH when
+// name is not a subcommand of the root command, e.g. prefix "mytool-"
+func WithExternalPlugins(prefix string) Option {
+	return func(o *cliOptions) {
+		o.pluginPrefix = prefix
+	}
+}
+
 // WithGlobalArgsEnabled enable global argumets
 func WithGlobalArgsEnabled() Option {
 	return func(o *cliOptions) {
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrPanic is returned by Run when a runner panics and panic recovery is enabled
+type ErrPanic struct {
+	// Command is the full name of the command that panicked
+	Command string
+	// Value is the value passed to panic
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the time of the panic
+	Stack []byte
+}
+
+func (e ErrPanic) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Command, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e ErrPanic) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// call executes f for command c, converting panics to ErrPanic when panic
+// recovery is enabled
+func (cli *CLI) call(ctx context.Context, c *command, f func(context.Context) error) (err error) {
+	if !cli.options.panicRecovery {
+		return f(ctx)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			perr := ErrPanic{
+				Command: c.FullName(),
+				Value:   r,
+				Stack:   debug.Stack(),
+			}
+			if cli.options.crashHandler != nil {
+				cli.options.crashHandler(perr)
+			}
+			err = perr
+		}
+	}()
+	return f(ctx)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// parentField is a field of a command struct tagged `cli:"parent"`
+type parentField struct {
+	path *path
+	cmd  *command
+}
+
+// AddParentField adds a field to be set to the closest ancestor command
+// assignable to typ. Panics if there is none
+func (c *command) AddParentField(p *path, typ reflect.Type) {
+	for anc := c.parent; anc != nil; anc = anc.parent {
+		if anc.path.Type().AssignableTo(typ) {
+			c.parentFlds = append(c.parentFlds, parentField{path: p, cmd: anc})
+			return
+		}
+	}
+	panic(fmt.Sprintf("no parent command of type %s for command: %s", typ, c.Name))
+}
+
+// SetParentFields sets the parent fields to the ancestor commands
+func (c *command) SetParentFields() {
+	for _, pf := range c.parentFlds {
+		pf.path.value().Set(pf.cmd.path.value())
+	}
+}
+
+// ParentFromContext returns the closest ancestor of the executed command of
+// type T. Use a pointer to the parent command struct as T
+func ParentFromContext[T any](ctx context.Context) (T, bool) {
+	var zero T
+	st, ok := ctx.Value(runKey{}).(*runState)
+	if !ok {
+		return zero, false
+	}
+	for i := len(st.runList) - 2; i >= 0; i-- {
+		if p, ok := st.runList[i].(T); ok {
+			return p, true
+		}
+	}
+	return zero, false
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"encoding"
 	"fmt"
 	"os"
 	"strconv"
@@ -23,14 +22,22 @@ func newParser(cli *CLI) *parser {
 
 type parser struct {
 	cli       *CLI
+	args      []string
 	globals   *flagSet
 	curArg    *argument
 	curCmd    *command
 	curPos    int
 	allPos    bool
 	runList   []interface{}
+	cmdList   []*command
+	plugin    *pluginRunner
 	isComp    bool
 	expectCmd bool
+	expectVal bool
+	idx       int
+	tok       parserToken
+	lastTok   parserToken
+	expanded  map[string]bool
 	debug     bool
 }
 
@@ -46,79 +53,113 @@ const (
 
 type StateFunc func(s string, t parserToken) (StateFunc, error)
 
-func (p *parser) Run(args []string) (err error) {
+func (p *parser) Run(args []string) error {
+	if isCompletion() {
+		if words, err := parseCompletion(args); err == nil {
+			for _, v := range p.Complete(words) {
+				fmt.Fprintln(p.cli.completeOut, v)
+			}
+		}
+		p.cli.osExit(0)
+		return nil
+	}
+	return p.parse(args)
+}
+
+// Complete returns the completion candidates of the last of args
+func (p *parser) Complete(args []string) []string {
+	p.isComp = true
+	if len(args) < 2 || p.parse(args) != nil {
+		return nil
+	}
+	val := p.args[len(p.args)-1]
+	if p.plugin != nil {
+		return p.plugin.Complete(val)
+	}
+	if p.allPos {
+		return nil
+	}
+	var completer Completer
+	if p.currentCmd().HasSubcommands() {
+		completer = NewFuncCmpleter(p.currentCmd().CompleteSubcommands)
+	} else {
+		completer = NewFuncCmpleter(p.currentCmd().CompleteFlags)
+	}
+	switch p.tok {
+	case tokCOMPFLAG:
+		fg, vl := splitCompositeFlag(val)
+		flg := p.currentCmd().GetFlag(fg)
+		if flg != nil {
+			completer = flg
+			val = vl
+		}
+	case tokVAL:
+		if p.lastTok == tokFLAG && !p.currentArg().IsBool() {
+			completer = p.currentArg()
+		}
+	case tokFLAG, tokALLPOS:
+		completer = NewFuncCmpleter(p.currentCmd().CompleteFlags)
+	}
+	if pfx := p.cli.options.responsePrefix; pfx != 0 && strings.HasPrefix(val, string(pfx)) {
+		completer = NewFuncCmpleter(p.cli.completeResponseFile)
+	}
+	if completer == nil {
+		return nil
+	}
+	return completer.Complete(val)
+}
 
-	isComp := isCompletion()
-	if isComp {
-		p.isComp = true
-		args, err = parseCompletion(args)
+// parse runs the state machine over args. In completion the last arg is
+// not parsed
+func (p *parser) parse(args []string) (err error) {
+	if p.cli.options.responsePrefix != 0 && len(args) > 1 {
+		// the word being completed is not expanded
+		last := len(args)
+		if p.isComp {
+			last--
+		}
+		words, err := p.cli.expandResponseFiles(args[1:last])
 		if err != nil {
-			p.cli.osExit(0)
+			return err
 		}
+		args = append(append(args[:1:1], words...), args[last:]...)
 	}
 
 	c, err := p.cli.findRootCommand(args[0])
 	if err != nil {
-		if isComp {
-			p.cli.osExit(0)
-		}
 		return err
 	}
 	p.setCurrentCmd(c)
 
-	args = args[1:]
+	p.args = args[1:]
 	state := p.entryState
-	var t, lt parserToken
 
-	for i, a := range args {
-		lt = t
-		t = p.tokenType(a)
+	// args are spliced by alias expansion so they are indexed on every iteration
+	for i := 0; i < len(p.args); i++ {
+		// the word being completed is not expanded
+		if !p.isComp || i < len(p.args)-1 {
+			if err := p.expandAlias(i); err != nil {
+				return err
+			}
+		}
+		a := p.args[i]
+		p.lastTok = p.tok
+		p.tok = p.tokenType(a)
 		if p.allPos {
-			t = tokVAL
+			p.tok = tokVAL
 		}
-		if p.isComp && i == len(args)-1 {
-			break
+		if p.isComp && i == len(p.args)-1 {
+			return nil
 		}
-		state, err = state(a, t)
+		// index in the args
+		p.idx = i + 1
+		state, err = state(a, p.tok)
 		if err != nil {
-			if isComp {
-				p.cli.osExit(0)
-			}
 			return err
 		}
 	}
-	if p.isComp {
-		if p.allPos {
-			p.cli.osExit(0)
-		}
-		var completer Completer
-		if p.currentCmd().HasSubcommands() {
-			completer = NewFuncCmpleter(p.currentCmd().CompleteSubcommands)
-		} else {
-			completer = NewFuncCmpleter(p.currentCmd().CompleteFlags)
-		}
-		val := args[len(args)-1]
-		switch t {
-		case tokCOMPFLAG:
-			fg, vl := splitCompositeFlag(val)
-			flg := p.currentCmd().GetFlag(fg)
-			if flg != nil {
-				completer = flg
-				val = vl
-			}
-		case tokVAL:
-			if lt == tokFLAG && !p.currentArg().IsBool() {
-				completer = p.currentArg()
-			}
-		case tokFLAG, tokALLPOS:
-			completer = NewFuncCmpleter(p.currentCmd().CompleteFlags)
-		}
-		if completer != nil {
-			for _, v := range completer.Complete(val) {
-				fmt.Fprintln(p.cli.completeOut, v)
-			}
-		}
-		p.cli.osExit(0)
+	if p.expectVal {
+		return ErrMissingValue{Flag: p.currentArg().long, Index: p.idx}
 	}
 	return nil
 }
@@ -127,6 +168,10 @@ func (p *parser) RunList() []interface{} {
 	return p.runList
 }
 
+func (p *parser) CmdList() []*command {
+	return p.cmdList
+}
+
 func (p *parser) setCurrentCmd(c *command) {
 	p.curCmd = c
 	if p.cli.options.globalsEnabled {
@@ -138,6 +183,7 @@ func (p *parser) setCurrentCmd(c *command) {
 	}
 	// add subcommand to execution list
 	p.runList = append(p.runList, p.currentCmd().path.Get())
+	p.cmdList = append(p.cmdList, p.currentCmd())
 }
 
 func (p *parser) currentCmd() *command {
@@ -168,18 +214,39 @@ func (p *parser) entryState(s string, t parserToken) (StateFunc, error) {
 		p.allPos = true
 		return p.entryState, nil
 	default:
-		return nil, fmt.Errorf("unknown token: %d", t)
+		return nil, ErrUnexpectedToken{Token: s, Index: p.idx}
 	}
 }
 
 func (p *parser) cmdState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("cmdState", s, t)
 	if t != tokCMD {
-		return nil, fmt.Errorf("unexpected token: %d at cmdState", t)
+		return nil, ErrUnexpectedToken{Token: s, Index: p.idx}
 	}
 	cc, ok := p.currentCmd().LookupSubcommand(s)
+	if !ok && p.cli.options.prefixMatching {
+		switch matches := p.currentCmd().MatchSubcommands(s); len(matches) {
+		case 0:
+		case 1:
+			cc, ok = matches[0], true
+		default:
+			err := ErrAmbiguousCommand{Command: s}
+			for _, m := range matches {
+				err.Candidates = append(err.Candidates, m.Name)
+			}
+			return nil, err
+		}
+	}
 	if !ok {
-		return nil, ErrCommandNotFound{s}
+		if file, ok := p.cli.lookupPlugin(p.currentCmd(), s); ok {
+			return p.startPlugin(s, file)
+		}
+		names := append(p.currentCmd().SubcommandNames(), p.currentCmd().Plugins()...)
+		names = append(names, p.currentCmd().UserAliases()...)
+		return nil, ErrCommandNotFound{
+			Command:     s,
+			Suggestions: suggest(s, names, p.cli.options.suggestDistance),
+		}
 	}
 	p.setCurrentCmd(cc)
 	return p.entryState, nil
@@ -188,10 +255,10 @@ func (p *parser) cmdState(s string, t parserToken) (StateFunc, error) {
 func (p *parser) posArgState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("posArgState", s, t)
 	if t != tokVAL {
-		return nil, fmt.Errorf("unexpected token: %d at posArgState", t)
+		return nil, ErrUnexpectedToken{Token: s, Index: p.idx}
 	}
 	if p.curPos == len(p.currentCmd().positionals) {
-		return nil, fmt.Errorf("too many positional arguments")
+		return nil, ErrTooManyPositionals{Value: s, Index: p.idx}
 	}
 	a := p.currentCmd().positionals[p.curPos]
 	p.setCurrentArg(a)
@@ -205,15 +272,9 @@ func (p *parser) posArgState(s string, t parserToken) (StateFunc, error) {
 func (p *parser) valueState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("valueState", s, t)
 	if t != tokVAL {
-		return nil, fmt.Errorf("unexpected token: %d at valueState", t)
-	}
-	a := p.currentArg()
-	if tum, ok := a.path.Get().(encoding.TextUnmarshaler); ok {
-		if err := tum.UnmarshalText([]byte(s)); err != nil {
-			return nil, err
-		}
-		return p.entryState, nil
+		return nil, ErrMissingValue{Flag: p.currentArg().long, Index: p.idx - 1}
 	}
+	p.expectVal = false
 	if err := p.currentArg().SetValue(s); err != nil {
 		return nil, err
 	}
@@ -225,19 +286,23 @@ func (p *parser) valueState(s string, t parserToken) (StateFunc, error) {
 func (p *parser) sliceValueState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("sliceValueState", s, t)
 	if t != tokVAL {
-		return nil, fmt.Errorf("unexpected token: %d at sliceValueState", t)
+		return nil, ErrMissingValue{Flag: p.currentArg().long, Index: p.idx - 1}
 	}
+	p.expectVal = false
 	a := p.currentArg()
 	if err := a.Append(s); err != nil {
 		return nil, err
 	}
+	if p.currentCmd().HasSubcommands() {
+		p.expectCmd = true
+	}
 	return p.entryState, nil
 }
 
 func (p *parser) flagState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("flagState", s, t)
 	if t != tokFLAG {
-		return nil, fmt.Errorf("unexpected token: %d at flagState", t)
+		return nil, ErrUnexpectedToken{Token: s, Index: p.idx}
 	}
 	if p.cli.isHelp(s) {
 		p.currentCmd().Usage(p.cli.helpOut)
@@ -246,21 +311,16 @@ func (p *parser) flagState(s string, t parserToken) (StateFunc, error) {
 	if p.cli.isVersion(s) {
 		// handle version
 	}
-	a := p.currentCmd().GetFlag(s)
-	if a == nil {
-		if p.cli.options.globalsEnabled {
-			if a = p.globals.Get(s); a == nil {
-				return nil, ErrNoSuchFlag{s}
-			}
-		} else {
-			return nil, ErrNoSuchFlag{s}
-		}
+	a, err := p.lookupFlag(s)
+	if err != nil {
+		return nil, err
 	}
 	p.setCurrentArg(a)
 	if a.IsBool() {
 		return p.valueState("true", tokVAL)
 	}
 	p.expectCmd = false
+	p.expectVal = true
 	if a.isSlice {
 		return p.sliceValueState, nil
 	}
@@ -270,29 +330,65 @@ func (p *parser) flagState(s string, t parserToken) (StateFunc, error) {
 func (p *parser) compositFlagState(s string, t parserToken) (StateFunc, error) {
 	p.debugln("compositFlagState", s, t)
 	if t != tokCOMPFLAG {
-		return nil, fmt.Errorf("unexpected token: %d at compositFlagState", t)
+		return nil, ErrUnexpectedToken{Token: s, Index: p.idx}
 	}
 	i := strings.Index(s, "=")
 	flg := s[:i]
 	val := s[i+1:]
-	a := p.currentCmd().GetFlag(flg)
-	if a == nil {
-		if p.cli.options.globalsEnabled {
-			fmt.Println("glob", p.globals.All())
-			if a = p.globals.Get(s); a == nil {
-				return nil, ErrNoSuchFlag{s}
-			}
-		} else {
-			return nil, ErrNoSuchFlag{s}
-		}
+	a, err := p.lookupFlag(flg)
+	if err != nil {
+		return nil, err
 	}
 	p.setCurrentArg(a)
 	if a.isSlice {
-		return p.sliceValueState(s, tokVAL)
+		return p.sliceValueState(val, tokVAL)
 	}
 	return p.valueState(val, tokVAL)
 }
 
+// lookupFlag gets the flag from the current command or the globals
+func (p *parser) lookupFlag(name string) (*argument, error) {
+	if a := p.currentCmd().GetFlag(name); a != nil {
+		return a, nil
+	}
+	if p.cli.options.globalsEnabled {
+		if a := p.globals.Get(name); a != nil {
+			return a, nil
+		}
+	}
+	if p.cli.options.abbreviations && strings.HasPrefix(name, "--") && len(name) > 2 {
+		matches := p.currentCmd().flags.Match(name)
+		if p.cli.options.globalsEnabled {
+			for _, a := range p.globals.Match(name) {
+				if p.currentCmd().GetFlag(a.long) != a {
+					matches = append(matches, a)
+				}
+			}
+		}
+		switch len(matches) {
+		case 0:
+		case 1:
+			return matches[0], nil
+		default:
+			err := ErrAmbiguousFlag{Flag: name}
+			for _, a := range matches {
+				err.Candidates = append(err.Candidates, a.long)
+			}
+			return nil, err
+		}
+	}
+	err := ErrNoSuchFlag{Flag: name}
+	// suggest only for long flags, short ones are all too close to each other
+	if strings.HasPrefix(name, "--") {
+		candidates := p.currentCmd().flags.Longs()
+		if p.cli.options.globalsEnabled {
+			candidates = append(candidates, p.globals.Longs()...)
+		}
+		err.Suggestions = suggest(name, candidates, p.cli.options.suggestDistance)
+	}
+	return nil, err
+}
+
 func (p *parser) tokenType(s string) parserToken {
 	if isFlag(s) {
 		if i := strings.Index(s, "="); i != -1 {
@@ -325,12 +421,17 @@ func parseCompletion(args []string) ([]string, error) {
 	if len(line) > point {
 		line = line[:point]
 	}
+	return splitCompletionLine(line)
+}
+
+// splitCompletionLine splits line in words. An empty word is added to be
+// completed if line ends with a space
+func splitCompletionLine(line string) ([]string, error) {
 	wrds, err := shellquote.Split(line)
 	if err != nil {
 		return nil, err
 	}
-	isLastSpace := line[len(line)-1] == ' '
-	if isLastSpace {
+	if strings.HasSuffix(line, " ") {
 		wrds = append(wrds, "")
 	}
 	return wrds, nil
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -16,10 +17,23 @@ type path struct {
 func (p *path) Subpath(name string) *path {
 	return &path{
 		root: p.root,
-		path: append(p.path, name),
+		path: append(p.path[:len(p.path):len(p.path)], name),
 	}
 }
 
+// Type returns the type of the value without allocating any nil pointers
+func (p *path) Type() reflect.Type {
+	t := p.root.Type()
+	for _, s := range p.path {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		f, _ := t.FieldByName(s)
+		t = f.Type
+	}
+	return t
+}
+
 func (p *path) Get() interface{} {
 	return p.value().Interface()
 }
@@ -56,6 +70,22 @@ func (p *path) valueDeref() reflect.Value {
 	return v
 }
 
+// lookup returns the value without allocating nil pointers. Reports false
+// if a pointer on the way is nil
+func (p *path) lookup() (reflect.Value, bool) {
+	v := *p.root
+	for _, s := range p.path {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(s)
+	}
+	return v, true
+}
+
 func (p *path) value() reflect.Value {
 	if p.val != nil {
 		return *p.val
@@ -90,28 +120,36 @@ func setScalarValue(v reflect.Value, s string) error {
 		} else if strings.ToLower(s) == "false" {
 			v.SetBool(false)
 		} else {
-			return ErrInvalidValue(s, "")
+			return errors.New("must be true or false")
 		}
 	case isString(v.Type()):
 		v.SetString(s)
 	case isFloat(v.Type()):
-		f, err := strconv.ParseFloat(s, 64)
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
 		if err != nil {
-			return err
+			return numError(err)
 		}
 		v.SetFloat(f)
 	case isInt(v.Type()):
-		i, err := strconv.ParseInt(s, 10, 64)
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
 		if err != nil {
-			return err
+			return numError(err)
 		}
 		v.SetInt(i)
 	case isUint(v.Type()):
-		ui, err := strconv.ParseUint(s, 10, 64)
+		ui, err := strconv.ParseUint(s, 10, v.Type().Bits())
 		if err != nil {
-			return err
+			return numError(err)
 		}
 		v.SetUint(ui)
 	}
 	return nil
 }
+
+// numError strips the function & input from strconv errors
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// pluginRunner executes an external plugin with the args following its name
+type pluginRunner struct {
+	file    string
+	args    []string
+	parents []*command
+}
+
+func (r *pluginRunner) Run(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, r.file, r.args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), r.Env()...)
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() > 0 {
+		return Exit(err, ee.ExitCode())
+	}
+	return err
+}
+
+// Env returns the flags of the parent commands having a value as variables
+// named by their primary env name
+func (r *pluginRunner) Env() (out []string) {
+	for _, c := range r.parents {
+		for _, a := range c.Flags() {
+			if len(a.env) == 0 || !a.IsSet() {
+				continue
+			}
+			out = append(out, a.env[0].name+"="+a.valueString(a.path.valueDeref()))
+		}
+	}
+	return
+}
+
+// Complete delegates the completion of the last word to the plugin by
+// running it with the completion line rewritten
+func (r *pluginRunner) Complete(last string) []string {
+	line := shellquote.Join(append([]string{filepath.Base(r.file)}, r.args...)...) + " "
+	if last != "" {
+		line += shellquote.Join(last)
+	}
+	cmd := exec.Command(r.file)
+	cmd.Env = append(os.Environ(), r.Env()...)
+	cmd.Env = append(cmd.Env, "COMP_LINE="+line, "COMP_POINT="+strconv.Itoa(len(line)))
+	out, err := cmd.Output()
+	if err != nil || len(out) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+}
+
+// lookupPlugin returns the executable of the plugin name of command c. Only
+// root commands have plugins
+func (cli *CLI) lookupPlugin(c *command, name string) (string, bool) {
+	pfx := cli.options.pluginPrefix
+	if pfx == "" || c.parent != nil || strings.ContainsRune(name, filepath.Separator) {
+		return "", false
+	}
+	file, err := exec.LookPath(pfx + name)
+	return file, err == nil
+}
+
+// Plugins returns the names of the plugins of the command found in PATH,
+// subcommands taking precedence
+func (c *command) Plugins() (out []string) {
+	pfx := c.opts.pluginPrefix
+	if pfx == "" || c.parent != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, sc := range c.subcmds {
+		seen[sc.Name] = true
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := strings.TrimPrefix(e.Name(), pfx)
+			if name == e.Name() || name == "" || seen[name] {
+				continue
+			}
+			fi, err := os.Stat(filepath.Join(dir, e.Name()))
+			if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return
+}
+
+func (c *command) PluginDescription() (out []string) {
+	for _, name := range c.Plugins() {
+		out = append(out, c.cmdColumns(name, "external command "+c.opts.pluginPrefix+name))
+	}
+	return
+}
+
+// startPlugin adds the plugin to the command chain. The remaining args are
+// passed to the plugin without parsing
+func (p *parser) startPlugin(name, file string) (StateFunc, error) {
+	p.plugin = &pluginRunner{
+		file:    file,
+		parents: append([]*command{}, p.cmdList...),
+	}
+	v := reflect.ValueOf(p.plugin)
+	p.setCurrentCmd(&command{
+		Name:       name,
+		path:       &path{root: &v},
+		parent:     p.currentCmd(),
+		subcmdsMap: map[string]*command{},
+		flags:      newFlagSet(),
+		opts:       p.cli.options,
+	})
+	return p.pluginState, nil
+}
+
+func (p *parser) pluginState(s string, t parserToken) (StateFunc, error) {
+	p.debugln("pluginState", s, t)
+	p.plugin.args = append(p.plugin.args, s)
+	return p.pluginState, nil
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// promptAttempts is the number of times an invalid value is asked again
+const promptAttempts = 3
+
+// prompter asks for the values of the missing required arguments
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// noEcho turns off the echo of the terminal for secrets
+	noEcho func() (func(), error)
+}
+
+// newPrompter returns a prompter reading stdin, nil if prompting is disabled
+// or stdin is not a terminal
+func (cli *CLI) newPrompter() *prompter {
+	if cli.options.noPrompt || isCompletion() || !cli.isTerminal(cli.stdin) {
+		return nil
+	}
+	pr := &prompter{
+		in:  bufio.NewReader(cli.stdin),
+		out: cli.errorOut,
+	}
+	if f, ok := cli.stdin.(*os.File); ok {
+		pr.noEcho = func() (func(), error) {
+			return disableEcho(f.Fd())
+		}
+	}
+	return pr
+}
+
+func readerIsTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// Prompt asks for the value of a until a valid one is entered. The values of
+// enums & oneof are listed to be selected by number. An empty answer keeps the
+// default, shown in brackets. The error of the last invalid value is returned
+// when no attempt succeeds
+func (pr *prompter) Prompt(a *argument) (lastErr error) {
+	text := a.prompt
+	if text == "" {
+		text = strings.TrimLeft(a.Name(), "-")
+	}
+	choices := a.oneOf
+	if a.enum != nil {
+		choices = a.enum.Names()
+	}
+	for i, c := range choices {
+		fmt.Fprintf(pr.out, "  %d) %s\n", i+1, c)
+	}
+	for i := 0; i < promptAttempts; i++ {
+		fmt.Fprint(pr.out, text)
+		if a.def != nil && !a.secret {
+			fmt.Fprintf(pr.out, " [%s]", strings.Join(a.def, " "))
+		}
+		fmt.Fprint(pr.out, ": ")
+		val, err := pr.readLine(a.secret)
+		if err == io.EOF {
+			return lastErr
+		}
+		if err != nil {
+			return err
+		}
+		if val == "" {
+			if a.def != nil {
+				return nil
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(val); err == nil && n >= 1 && n <= len(choices) && !isChoice(choices, val) {
+			val = choices[n-1]
+		}
+		if lastErr = a.setPrompted(val); lastErr != nil {
+			fmt.Fprintln(pr.out, lastErr)
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// isChoice reports whether val is one of the choices
+func isChoice(choices []string, val string) bool {
+	for _, c := range choices {
+		if c == val {
+			return true
+		}
+	}
+	return false
+}
+
+func (pr *prompter) readLine(secret bool) (string, error) {
+	if secret && pr.noEcho != nil {
+		if restore, err := pr.noEcho(); err == nil {
+			defer restore()
+		}
+	}
+	line, err := pr.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if secret {
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	return strings.TrimSpace(line), err
+}
+
+// setPrompted sets the value entered at the prompt. The argument is cleared
+// if the value is not valid
+func (a *argument) setPrompted(val string) (err error) {
+	a.setSource(sourcePrompt)
+	if a.isSlice {
+		var words []string
+		if words, err = shellquote.Split(val); err == nil {
+			for _, s := range words {
+				if err = a.Append(s); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		err = a.SetValue(val)
+	}
+	if err != nil {
+		v := a.path.valueDeref()
+		v.Set(reflect.Zero(v.Type()))
+		a.Reset()
+	}
+	return err
+}
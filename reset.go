@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"reflect"
+)
+
+// Reset restores the structs of default CLI
+func Reset() {
+	defaultCLI.Reset()
+}
+
+// Reset restores the fields set by parsing to their values before the first
+// Parse and clears the state of the arguments. Other fields of the command
+// structs are left alone. Parse calls it when parsing again
+func (cli *CLI) Reset() {
+	for _, c := range cli.cmds {
+		for _, f := range c.initial {
+			f.restore()
+		}
+		c.reset()
+	}
+	cli.runList, cli.cmdList, cli.errCmd = nil, nil, nil
+}
+
+// snapshot saves the fields set by parsing of the commands not saved yet to
+// be restored by Reset
+func (cli *CLI) snapshot() {
+	for _, c := range cli.cmds {
+		if c.initial == nil {
+			c.initial = c.snapshot([]fieldState{})
+		}
+	}
+}
+
+// fieldState is the value of a field set by parsing before the first Parse
+type fieldState struct {
+	path *path
+	// orig is the value of the field, invalid if the field is under a nil
+	// pointer
+	orig reflect.Value
+	// copy is a deep copy of the value of argument fields
+	copy reflect.Value
+	// arg is the argument of the field with its state
+	arg       *argument
+	isSet     bool
+	source    valueSource
+	sourceEnv string
+}
+
+// snapshot appends the state of the fields of the command & its subcommands
+// set by parsing: pointers to subcommands & argument structs, parent &
+// inject fields and argument values. Pointers are saved before the fields
+// under them to be restored first
+func (c *command) snapshot(out []fieldState) []fieldState {
+	for _, p := range c.argStructs {
+		out = append(out, saveField(p, false))
+	}
+	for _, pf := range c.parentFlds {
+		out = append(out, saveField(pf.path, false))
+	}
+	for _, p := range c.injectFlds {
+		out = append(out, saveField(p, false))
+	}
+	for _, a := range c.Args() {
+		f := saveField(a.path, true)
+		f.arg, f.isSet, f.source, f.sourceEnv = a, a.isSet, a.source, a.sourceEnv
+		out = append(out, f)
+	}
+	for _, sc := range c.subcmds {
+		out = append(out, saveField(sc.path, false))
+		out = sc.snapshot(out)
+	}
+	return out
+}
+
+func saveField(p *path, deep bool) fieldState {
+	f := fieldState{path: p}
+	v, ok := p.lookup()
+	if !ok {
+		return f
+	}
+	f.orig = reflect.New(v.Type()).Elem()
+	f.orig.Set(v)
+	if deep {
+		f.copy = deepCopy(v)
+	}
+	return f
+}
+
+// restore sets the field to its saved value. Arguments get a copy of their
+// value, written through their pointer if they had one, and their state
+func (f fieldState) restore() {
+	if f.arg != nil {
+		f.arg.isSet, f.arg.source, f.arg.sourceEnv = f.isSet, f.source, f.sourceEnv
+	}
+	if !f.orig.IsValid() {
+		return
+	}
+	v, ok := f.path.lookup()
+	if !ok {
+		return
+	}
+	v.Set(f.orig)
+	if !f.copy.IsValid() {
+		return
+	}
+	if f.orig.Kind() == reflect.Ptr && !f.orig.IsNil() {
+		v.Elem().Set(deepCopy(f.copy.Elem()))
+		return
+	}
+	v.Set(deepCopy(f.copy))
+}
+
+// reset clears the cached values of the paths and the state of the arguments
+// of the command & its subcommands
+func (c *command) reset() {
+	c.clearCache()
+	for _, a := range c.Args() {
+		a.Reset()
+	}
+	for _, sc := range c.subcmds {
+		sc.reset()
+	}
+}
+
+// clearCache clears the cached values of the paths of the command & its
+// subcommands
+func (c *command) clearCache() {
+	c.path.val = nil
+	for _, p := range c.argStructs {
+		p.val = nil
+	}
+	for _, pf := range c.parentFlds {
+		pf.path.val = nil
+	}
+	for _, p := range c.injectFlds {
+		p.val = nil
+	}
+	for _, a := range c.Args() {
+		a.path.val = nil
+	}
+	for _, sc := range c.subcmds {
+		sc.clearCache()
+	}
+}
+
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// deepCopy returns a copy of v not sharing pointers, slices & maps with it.
+// Unexported fields are copied as is. Used for argument values only
+func deepCopy(v reflect.Value) reflect.Value {
+	return deepCopyValue(v, map[copyKey]reflect.Value{})
+}
+
+func deepCopyValue(v reflect.Value, seen map[copyKey]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := copyKey{v.Pointer(), v.Type()}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[key] = c
+		c.Elem().Set(deepCopyValue(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopyValue(v.Field(i), seen))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopyValue(iter.Value(), seen))
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// maxResponseDepth is the max nesting of response files
+const maxResponseDepth = 10
+
+// isResponseFile reports whether the arg names a response file
+func (cli *CLI) isResponseFile(arg string) bool {
+	pfx := cli.options.responsePrefix
+	return pfx != 0 && strings.HasPrefix(arg, string(pfx)) && len(arg) > len(string(pfx))
+}
+
+// expandResponseFiles replaces the response file args by the shell quoted
+// words of the files. Args after "--" are not expanded
+func (cli *CLI) expandResponseFiles(args []string) ([]string, error) {
+	return cli.expandResponse(args, 0, map[string]bool{})
+}
+
+func (cli *CLI) expandResponse(args []string, depth int, open map[string]bool) ([]string, error) {
+	out := []string{}
+	for i, a := range args {
+		if a == "--" {
+			return append(out, args[i:]...), nil
+		}
+		if !cli.isResponseFile(a) {
+			out = append(out, a)
+			continue
+		}
+		file := a[len(string(cli.options.responsePrefix)):]
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		if open[abs] {
+			return nil, usageErrorf("response file includes itself: %s", file)
+		}
+		if depth == maxResponseDepth {
+			return nil, usageErrorf("response files nested too deep: %s", file)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("response file: %w", err)
+		}
+		words, err := shellquote.Split(string(data))
+		if err != nil {
+			return nil, usageErrorf("invalid response file %s: %v", file, err)
+		}
+		open[abs] = true
+		words, err = cli.expandResponse(words, depth+1, open)
+		if err != nil {
+			return nil, err
+		}
+		delete(open, abs)
+		out = append(out, words...)
+	}
+	return out, nil
+}
+
+// completeResponseFile completes the file of a response file arg
+func (cli *CLI) completeResponseFile(val string) (out []string) {
+	pfx := string(cli.options.responsePrefix)
+	for _, f := range filesCompleter(strings.TrimPrefix(val, pfx)) {
+		out = append(out, pfx+f)
+	}
+	return
+}
@@ -24,7 +24,59 @@ type lastErrorKey struct{}
 
 // LastErrorFromContext get the last error in case the execution continues on errors
 func LastErrorFromContext(ctx context.Context) error {
-	return ctx.Value(lastErrorKey{}).(error)
+	err, _ := ctx.Value(lastErrorKey{}).(error)
+	return err
+}
+
+type cliKey struct{}
+
+func cliFromContext(ctx context.Context) *CLI {
+	cli, _ := ctx.Value(cliKey{}).(*CLI)
+	return cli
+}
+
+type runKey struct{}
+
+// runState is the command chain being executed
+type runState struct {
+	cmds    []*command
+	runList []interface{}
+}
+
+// CommandPath returns the names of the executed command chain, root first
+func CommandPath(ctx context.Context) []string {
+	st, ok := ctx.Value(runKey{}).(*runState)
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(st.cmds))
+	for _, c := range st.cmds {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+// CommandFromContext returns the struct of the executed command, the last in
+// the chain
+func CommandFromContext(ctx context.Context) interface{} {
+	st, ok := ctx.Value(runKey{}).(*runState)
+	if !ok || len(st.runList) == 0 {
+		return nil
+	}
+	return st.runList[len(st.runList)-1]
+}
+
+// RunFunc executes the command chain
+type RunFunc func(ctx context.Context) error
+
+// Middleware wraps the execution of the command chain. CommandPath and
+// CommandFromContext can be used to get the command being executed
+type Middleware func(next RunFunc) RunFunc
+
+// Middlewarer is implemented by commands wrapping the execution of the chain
+// they are part of
+type Middlewarer interface {
+	Middleware(next RunFunc) RunFunc
 }
 
 // Runner interface
@@ -57,74 +109,121 @@ func Run(ctx context.Context) error {
 	return defaultCLI.Run(ctx)
 }
 
-// Execute the chain of commands
+// Execute the chain of commands. When more than one error occurs, the one
+// selected by WithErrorSelection is returned
 func (cli *CLI) Run(ctx context.Context) error {
+	ctx = context.WithValue(ctx, cliKey{}, cli)
+	ctx = context.WithValue(ctx, runKey{}, &runState{
+		cmds:    cli.cmdList,
+		runList: cli.runList,
+	})
+
+	if len(cli.options.signals) > 0 {
+		var stop func()
+		ctx, stop = cli.handleSignals(ctx)
+		defer stop()
+	}
+
+	// middleware of the CLI wrap the middleware of the commands, root first
+	run := RunFunc(cli.run)
+	for i := len(cli.runList) - 1; i >= 0; i-- {
+		if mw, ok := cli.runList[i].(Middlewarer); ok {
+			run = mw.Middleware(run)
+		}
+	}
+	for i := len(cli.options.middleware) - 1; i >= 0; i-- {
+		run = cli.options.middleware[i](run)
+	}
+
+	err := run(ctx)
+	if sig := SignalFromContext(ctx); sig != nil {
+		return ErrSignal{Signal: sig, Err: err}
+	}
+	return err
+}
+
+// run executes the runners of the command chain
+func (cli *CLI) run(ctx context.Context) error {
+	errs := []error{}
+	// failed records err and reports if execution must break
+	failed := func(err error) bool {
+		if err == nil {
+			return false
+		}
+		errs = append(errs, err)
+		ctx = context.WithValue(ctx, lastErrorKey{}, err)
+		return cli.options.strategy != OnErrorContinue
+	}
+
+	// cleanups of the providers run after the post runners
+	in := cli.newInjector()
+	defer in.Cleanup()
 
-	var err error
-	lastCmd := len(cli.runList) - 1
+	// the chain can be replaced while running, e.g. by the shell
+	runList, cmdList := cli.runList, cli.cmdList
+	lastCmd := len(runList) - 1
 	pPostRunners := []PersistentPostRunner{}
+	pPostCmds := []*command{}
 
-	for i, inf := range cli.runList {
+	for i, inf := range runList {
+		c := cmdList[i]
+		// inject fields before the first hook of the command
+		inject := func(ctx context.Context) error {
+			return in.Inject(ctx, c)
+		}
+		if failed(cli.call(ctx, c, inject)) {
+			break
+		}
 		// PersistentPostRun pushed on a stack to run in a reverse order
 		if rnr, ok := inf.(PersistentPostRunner); ok {
 			pPostRunners = append([]PersistentPostRunner{rnr}, pPostRunners...)
+			pPostCmds = append([]*command{c}, pPostCmds...)
 		}
 		// PersistentPreRun
 		if rnr, ok := inf.(PersistentPreRunner); ok {
-			err = rnr.PersistentPreRun(ctx)
-			if err != nil {
-				if !(cli.options.strategy == OnErrorContinue) {
-					break
-				}
-				ctx = context.WithValue(ctx, lastErrorKey{}, err)
+			if failed(cli.call(ctx, c, rnr.PersistentPreRun)) {
+				break
 			}
 		}
 		if i == lastCmd {
 			// PreRun
 			if rnr, ok := inf.(PreRunner); ok {
-				err = rnr.PreRun(ctx)
-				if err != nil {
-					if !(cli.options.strategy == OnErrorContinue) {
-						break
-					}
-					ctx = context.WithValue(ctx, lastErrorKey{}, err)
+				if failed(cli.call(ctx, c, rnr.PreRun)) {
+					break
 				}
 			}
 			// Run
 			if rnr, ok := inf.(Runner); ok {
-				err = rnr.Run(ctx)
-				if err != nil {
-					if !(cli.options.strategy == OnErrorContinue) {
-						break
-					}
-					ctx = context.WithValue(ctx, lastErrorKey{}, err)
+				if failed(cli.call(ctx, c, rnr.Run)) {
+					break
+				}
+			} else if run := in.RunFunc(inf); run != nil {
+				if failed(cli.call(ctx, c, run)) {
+					break
 				}
 			}
 			// PostRun
 			if rnr, ok := inf.(PostRunner); ok {
-				err = rnr.PostRun(ctx)
-				if err != nil {
-					if !(cli.options.strategy == OnErrorContinue) {
-						break
-					}
-					ctx = context.WithValue(ctx, lastErrorKey{}, err)
+				if failed(cli.call(ctx, c, rnr.PostRun)) {
+					break
 				}
 			}
 		}
 	}
-	// check for error and strategy
-	if err != nil && cli.options.strategy == OnErrorBreak {
-		return err
+	// check for error and strategy. Post runners are always executed
+	// after a signal for cleanup
+	if len(errs) > 0 && cli.options.strategy == OnErrorBreak && SignalFromContext(ctx) == nil {
+		return cli.selectError(errs)
 	}
 	// PersistentPostRun
-	for _, rnr := range pPostRunners {
-		err = rnr.PersistentPostRun(ctx)
-		if err != nil {
+	for i, rnr := range pPostRunners {
+		if err := cli.call(ctx, pPostCmds[i], rnr.PersistentPostRun); err != nil {
+			errs = append(errs, err)
 			if cli.options.strategy == OnErrorPostRunners {
-				return err
+				break
 			}
 			ctx = context.WithValue(ctx, lastErrorKey{}, err)
 		}
 	}
-	return err
+	return cli.selectError(errs)
 }
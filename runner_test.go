@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+type exitTestCmd struct{}
+
+func (c *exitTestCmd) PersistentPreRun(ctx context.Context) error {
+	return Exit(errors.New("pre run failed"), 3)
+}
+
+func (c *exitTestCmd) Run(ctx context.Context) error {
+	return Exit(errors.New("run failed"), 4)
+}
+
+func TestExitCodes(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Options []Option
+		Code    int
+	}{
+		{"break", nil, 3},
+		{"continue last", []Option{WithOnErrorStrategy(OnErrorContinue)}, 4},
+		{"continue first", []Option{WithOnErrorStrategy(OnErrorContinue), WithErrorSelection(ErrorFirst)}, 3},
+		{"mapper", []Option{WithExitCodeMapper(func(err error) int { return 7 })}, 7},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			p := NewCLI(c.Options...)
+			p.NewCommand("root", &exitTestCmd{})
+			if err := p.Parse([]string{"root"}); err != nil {
+				t.Fatal(err)
+			}
+			err := p.Run(context.Background())
+			if code := p.ExitCode(err); code != c.Code {
+				t.Fatalf("exit code %d != %d", code, c.Code)
+			}
+		})
+	}
+
+	p := NewCLI()
+	if code := p.ExitCode(context.Canceled); code != ExitCanceled {
+		t.Fatalf("canceled exit code %d != %d", code, ExitCanceled)
+	}
+	if code := p.ExitCode(ErrNoSuchFlag{Flag: "--x"}); code != ExitUsage {
+		t.Fatalf("usage exit code %d != %d", code, ExitUsage)
+	}
+	if code := p.ExitCode(errors.New("x")); code != ExitError {
+		t.Fatalf("error exit code %d != %d", code, ExitError)
+	}
+}
+
+type signalTestCmd struct {
+	cleanup bool
+	signal  os.Signal
+}
+
+func (c *signalTestCmd) Run(ctx context.Context) error {
+	<-ctx.Done()
+	c.signal = SignalFromContext(ctx)
+	return ctx.Err()
+}
+
+func (c *signalTestCmd) PersistentPostRun(ctx context.Context) error {
+	c.cleanup = true
+	return nil
+}
+
+func TestSignalHandling(t *testing.T) {
+	cmd := &signalTestCmd{}
+	p := NewCLI(WithSignalHandling())
+	p.signalNotify = func(c chan<- os.Signal, sig ...os.Signal) {
+		go func() { c <- syscall.SIGTERM }()
+	}
+	p.NewCommand("root", cmd)
+	if err := p.Parse([]string{"root"}); err != nil {
+		t.Fatal(err)
+	}
+	err := p.Run(context.Background())
+	serr := ErrSignal{}
+	if !errors.As(err, &serr) || serr.Signal != syscall.SIGTERM || !errors.Is(err, context.Canceled) {
+		t.Fatal("should have returned ErrSignal, got:", err)
+	}
+	if code := p.ExitCode(err); code != 143 {
+		t.Fatalf("exit code %d != 143", code)
+	}
+	if cmd.signal != syscall.SIGTERM {
+		t.Fatal("signal should be available from context")
+	}
+	if !cmd.cleanup {
+		t.Fatal("persistent post runner should have been executed")
+	}
+}
+
+type panicTestCmd struct {
+	cleanup bool
+	Sub     *panicTestSubCmd
+}
+
+func (c *panicTestCmd) PersistentPostRun(ctx context.Context) error {
+	c.cleanup = true
+	return nil
+}
+
+type panicTestSubCmd struct{}
+
+func (c *panicTestSubCmd) Run(ctx context.Context) error {
+	panic("boom")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	cmd := &panicTestCmd{}
+	var crash ErrPanic
+	p := NewCLI(WithOnErrorStrategy(OnErrorPostRunners), WithCrashHandler(func(e ErrPanic) {
+		crash = e
+	}))
+	p.NewCommand("root", cmd)
+	if err := p.Parse([]string{"root", "sub"}); err != nil {
+		t.Fatal(err)
+	}
+	err := p.Run(context.Background())
+	perr := ErrPanic{}
+	if !errors.As(err, &perr) || perr.Value != "boom" || perr.Command != "root sub" || len(perr.Stack) == 0 {
+		t.Fatal("should have returned ErrPanic, got:", err)
+	}
+	if crash.Value != "boom" {
+		t.Fatal("crash handler should have been called")
+	}
+	if !cmd.cleanup {
+		t.Fatal("persistent post runner should have been executed")
+	}
+}
+
+type middlewareTestCmd struct {
+	calls *[]string
+	Sub   *middlewareTestSubCmd
+}
+
+func (c *middlewareTestCmd) Middleware(next RunFunc) RunFunc {
+	return func(ctx context.Context) error {
+		*c.calls = append(*c.calls, "root")
+		return next(ctx)
+	}
+}
+
+type middlewareTestSubCmd struct {
+	calls *[]string
+}
+
+func (c *middlewareTestSubCmd) Run(ctx context.Context) error {
+	*c.calls = append(*c.calls, "run")
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := []string{}
+	cmd := &middlewareTestCmd{
+		calls: &calls,
+		Sub:   &middlewareTestSubCmd{calls: &calls},
+	}
+	p := NewCLI(WithMiddleware(func(next RunFunc) RunFunc {
+		return func(ctx context.Context) error {
+			path := strings.Join(CommandPath(ctx), " ")
+			if _, ok := CommandFromContext(ctx).(*middlewareTestSubCmd); !ok {
+				t.Fatal("command should be *middlewareTestSubCmd")
+			}
+			calls = append(calls, "cli:"+path)
+			err := next(ctx)
+			calls = append(calls, "cli:done")
+			return err
+		}
+	}))
+	p.NewCommand("root", cmd)
+	if err := p.Parse([]string{"root", "sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(calls, ",") != "cli:root sub,root,run,cli:done" {
+		t.Fatal("wrong call order:", calls)
+	}
+}
+
+type parentTestRoot struct {
+	Token string
+	Sub   *parentTestSub
+}
+
+type parentTestSub struct {
+	Root *parentTestRoot `cli:"parent"`
+	Leaf *parentTestLeaf
+}
+
+type parentTestLeaf struct {
+	Root  *parentTestRoot `cli:"parent"`
+	token string
+	path  string
+}
+
+func (c *parentTestLeaf) Run(ctx context.Context) error {
+	root, ok := ParentFromContext[*parentTestRoot](ctx)
+	if !ok {
+		return errors.New("root not found in context")
+	}
+	if _, ok := ParentFromContext[*parentTestLeaf](ctx); ok {
+		return errors.New("leaf is not a parent")
+	}
+	c.token = root.Token
+	c.path = strings.Join(CommandPath(ctx), " ")
+	return nil
+}
+
+func TestParentAccess(t *testing.T) {
+	cmd := &parentTestRoot{}
+	p := NewCLI()
+	p.NewCommand("root", cmd)
+	if err := p.Parse([]string{"root", "--token", "secret", "sub", "leaf"}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Sub.Root != cmd || cmd.Sub.Leaf.Root != cmd {
+		t.Fatal("parent fields should point to root")
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Sub.Leaf.token != "secret" {
+		t.Fatal("leaf should have read the root token")
+	}
+	if cmd.Sub.Leaf.path != "root sub leaf" {
+		t.Fatal("wrong command path:", cmd.Sub.Leaf.path)
+	}
+}
+
+type injectTestDB struct {
+	dsn    string
+	closed bool
+}
+
+type injectTestLogger struct {
+	prefix string
+}
+
+type injectTestRoot struct {
+	DSN    string
+	Logger *injectTestLogger `inject:""`
+	Query  *injectTestQuery
+}
+
+type injectTestQuery struct {
+	Logger *injectTestLogger `inject:""`
+	result string
+}
+
+func (c *injectTestQuery) Run(ctx context.Context, db *injectTestDB, root *injectTestRoot) error {
+	c.result = c.Logger.prefix + db.dsn
+	return nil
+}
+
+func TestDependencyInjection(t *testing.T) {
+	cleanups := []string{}
+	var db *injectTestDB
+	cmd := &injectTestRoot{}
+	p := NewCLI()
+	p.NewCommand("root", cmd)
+	p.Provide(func() *injectTestLogger {
+		return &injectTestLogger{prefix: "db: "}
+	})
+	p.Provide(func(root *injectTestRoot, l *injectTestLogger) (*injectTestDB, func(), error) {
+		db = &injectTestDB{dsn: root.DSN}
+		return db, func() {
+			db.closed = true
+			cleanups = append(cleanups, "db")
+		}, nil
+	})
+	if err := p.Parse([]string{"root", "--dsn", "mem://", "query"}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Logger != nil {
+		t.Fatal("fields should be injected on run")
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Logger == nil || cmd.Logger != cmd.Query.Logger {
+		t.Fatal("same logger should be injected in all commands")
+	}
+	if cmd.Query.result != "db: mem://" {
+		t.Fatal("wrong result:", cmd.Query.result)
+	}
+	if !db.closed || len(cleanups) != 1 {
+		t.Fatal("db should be closed once")
+	}
+
+	// provider errors & cycles
+	p = NewCLI()
+	p.NewCommand("root", &injectTestRoot{})
+	p.Provide(func(db *injectTestDB) *injectTestLogger { return nil })
+	p.Provide(func(l *injectTestLogger) (*injectTestDB, error) { return nil, nil })
+	if err := p.Parse([]string{"root", "query"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Fatal("expected a dependency cycle error, got:", err)
+	}
+
+	p = NewCLI()
+	p.NewCommand("root", &injectTestRoot{})
+	p.Provide(func() (*injectTestLogger, error) { return nil, errors.New("no logger") })
+	if err := p.Parse([]string{"root"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "no logger") {
+		t.Fatal("expected the provider error, got:", err)
+	}
+
+	p = NewCLI(WithPanicRecovery())
+	p.NewCommand("root", &injectTestRoot{})
+	p.Provide(func() *injectTestLogger { panic("no logger") })
+	if err := p.Parse([]string{"root"}); err != nil {
+		t.Fatal(err)
+	}
+	perr := ErrPanic{}
+	if err := p.Run(context.Background()); !errors.As(err, &perr) || perr.Value != "no logger" {
+		t.Fatal("expected the provider panic recovered, got:", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic on invalid provider")
+			}
+		}()
+		p.Provide(func() (*injectTestLogger, int) { return nil, 0 })
+	}()
+}
+
+type pluginTestRoot struct {
+	Token string
+	Debug bool `env:"-"`
+	Sub   *struct{}
+}
+
+func TestExternalPlugins(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := "#!/bin/sh\n" +
+		"if [ -n \"$COMP_LINE\" ]; then echo \"$COMP_LINE|$COMP_POINT\"; exit 0; fi\n" +
+		"echo \"$@|$TOKEN|$DEBUG\" > " + out + "\n" +
+		"exit 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "ptool-hello"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	cmd := &pluginTestRoot{}
+	p := NewCLI(WithExternalPlugins("ptool-"))
+	p.NewCommand("ptool", cmd)
+	if err := p.Parse([]string{"ptool", "--token", "abc", "--debug", "hello", "--name", "x", "sub"}); err != nil {
+		t.Fatal(err)
+	}
+	err := p.Run(context.Background())
+	if ExitCode(err) != 3 {
+		t.Fatal("expected the exit code of the plugin, got:", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "--name x sub|abc|\n" {
+		t.Fatalf("wrong plugin args or env: %q", data)
+	}
+
+	// help & suggestions
+	buf := &bytes.Buffer{}
+	p.helpOut = buf
+	p.osExit = func(int) {}
+	p.cmds["ptool"].Usage(buf)
+	if !strings.Contains(buf.String(), "Plugins:\n    hello") {
+		t.Fatal("plugin not in help:", buf.String())
+	}
+	err = p.Parse([]string{"ptool", "helo"})
+	if e, ok := err.(ErrCommandNotFound); !ok || len(e.Suggestions) != 1 || e.Suggestions[0] != "hello" {
+		t.Fatal("expected a suggestion for the plugin, got:", err)
+	}
+
+	// completion
+	defer os.Unsetenv("COMP_LINE")
+	defer os.Unsetenv("COMP_POINT")
+	cases := []struct {
+		line   string
+		expect string
+	}{
+		{"ptool h", "hello \n"},
+		{"ptool hello --na", "ptool-hello --na|16\n"},
+		{"ptool hello a ", "ptool-hello a |14\n"},
+	}
+	for _, c := range cases {
+		os.Setenv("COMP_LINE", c.line)
+		os.Setenv("COMP_POINT", strconv.Itoa(len(c.line)))
+		buf := &bytes.Buffer{}
+		p.completeOut = buf
+		p.Parse([]string{"ptool"})
+		if buf.String() != c.expect {
+			t.Fatalf("wrong completion for %q: %q", c.line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kballard/go-shellquote"
+)
+
+type shellKey struct{}
+
+// shellExit is raised by osExit in the shell, e.g. by the help flag, to end
+// the line instead of the process
+type shellExit int
+
+// ShellCmd is a ready made `shell` command. Add it as a subcommand field of the
+// root command to run lines as the args of the root command, with history &
+// tab completion on terminals. `exit`, `quit` or Ctrl-D ends the shell
+type ShellCmd struct{}
+
+// Run reads & runs lines until the end of input
+func (s *ShellCmd) Run(ctx context.Context) error {
+	cli := cliFromContext(ctx)
+	if cli == nil || len(cli.cmdList) == 0 {
+		return errors.New("shell: not executed by a CLI")
+	}
+	if ctx.Value(shellKey{}) != nil {
+		return errors.New("shell: already in a shell")
+	}
+	ctx = context.WithValue(ctx, shellKey{}, true)
+	root := cli.cmdList[0]
+
+	defer cli.saveState()()
+
+	readLine := cli.lineReader(root.Name+"> ", func(line string) []string {
+		words, err := splitCompletionLine(root.Name + " " + line)
+		if err != nil {
+			return nil
+		}
+		cli.Reset()
+		return newParser(cli).Complete(words)
+	})
+	for ctx.Err() == nil {
+		line, err := readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		words, err := shellquote.Split(line)
+		if err != nil {
+			fmt.Fprintln(cli.errorOut, "error:", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if _, ok := root.LookupSubcommand(words[0]); !ok && (words[0] == "exit" || words[0] == "quit") {
+			return nil
+		}
+		if err := cli.runLine(ctx, append([]string{root.Name}, words...)); err != nil {
+			fmt.Fprintln(cli.errorOut, "error:", err)
+		}
+	}
+	return ctx.Err()
+}
+
+// saveState saves the command chain running a shell or a script and the
+// fields set by parsing, replaced by the lines it runs, and returns a func
+// restoring them for the rest of the chain
+func (cli *CLI) saveState() func() {
+	runList, cmdList := cli.runList, cli.cmdList
+	fields := []fieldState{}
+	for _, c := range cli.cmds {
+		fields = c.snapshot(fields)
+	}
+	return func() {
+		for _, f := range fields {
+			f.restore()
+		}
+		for _, c := range cli.cmds {
+			c.clearCache()
+		}
+		cli.runList, cli.cmdList = runList, cmdList
+	}
+}
+
+// runLine parses & runs args. Parse resets the state of the previous line
+func (cli *CLI) runLine(ctx context.Context, args []string) error {
+	exited, err := cli.parseLine(args)
+	if exited || err != nil {
+		return err
+	}
+	return cli.Run(ctx)
+}
+
+// parseLine parses args, reporting if parsing requested to exit
+func (cli *CLI) parseLine(args []string) (exited bool, err error) {
+	osExit := cli.osExit
+	defer func() {
+		cli.osExit = osExit
+		if r := recover(); r != nil {
+			if _, ok := r.(shellExit); !ok {
+				panic(r)
+			}
+			exited = true
+		}
+	}()
+	cli.osExit = func(code int) {
+		panic(shellExit(code))
+	}
+	return false, cli.Parse(args)
+}
+
+// lineReader returns a func reading lines from stdin, with a line editor
+// when stdin is a terminal
+func (cli *CLI) lineReader(prompt string, complete func(line string) []string) func() (string, error) {
+	if f, ok := cli.stdin.(*os.File); ok && isTerminal(f.Fd()) {
+		ed := &lineEditor{
+			in:       bufio.NewReader(f),
+			out:      cli.stdout,
+			prompt:   prompt,
+			complete: complete,
+		}
+		return func() (string, error) {
+			restore, err := makeRaw(f.Fd())
+			if err != nil {
+				return "", err
+			}
+			defer restore()
+			return ed.ReadLine()
+		}
+	}
+	sc := bufio.NewScanner(cli.stdin)
+	return func() (string, error) {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return sc.Text(), nil
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+type shellTestRoot struct {
+	Label  string
+	Shell  *ShellCmd
+	Greet  *shellTestGreet
+	Status *struct{}
+}
+
+var shellTestLabels []string
+
+func (c *shellTestRoot) PersistentPostRun(ctx context.Context) error {
+	shellTestLabels = append(shellTestLabels, c.Label)
+	return nil
+}
+
+type shellTestGreet struct {
+	Name string `cli:"required"`
+	Loud bool
+}
+
+var shellTestGreets []string
+
+func (c *shellTestGreet) Run(ctx context.Context) error {
+	s := "hello " + c.Name
+	if c.Loud {
+		s = strings.ToUpper(s)
+	}
+	shellTestGreets = append(shellTestGreets, s)
+	return nil
+}
+
+func TestShell(t *testing.T) {
+	shellTestGreets, shellTestLabels = nil, nil
+	cmd := &shellTestRoot{}
+	p := NewCLI()
+	p.NewCommand("root", cmd)
+	errOut := &bytes.Buffer{}
+	helpOut := &bytes.Buffer{}
+	p.errorOut = errOut
+	p.helpOut = helpOut
+	p.stdin = strings.NewReader(strings.Join([]string{
+		"greet --name bob --loud",
+		"",
+		"--label inner status",
+		"greet --name 'alice smith'",
+		"greet",
+		"greet --help",
+		"shell",
+		"nope",
+		"exit",
+		"greet --name never",
+	}, "\n"))
+
+	if err := p.Parse([]string{"root", "--label", "outer", "shell"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(shellTestGreets, ",") != "HELLO BOB,hello alice smith" {
+		t.Fatal("wrong lines executed:", shellTestGreets)
+	}
+	// the outer chain keeps its values after the lines
+	if strings.Join(shellTestLabels, ",") != ",inner,,outer" || cmd.Label != "outer" || p.cmdList[1].Name != "shell" {
+		t.Fatal("wrong labels of the post runs:", shellTestLabels)
+	}
+	expect := "error: required flag not set: --name (env: NAME)\n" +
+		"error: shell: already in a shell\n" +
+		"error: command not found: nope\n"
+	if errOut.String() != expect {
+		t.Fatalf("wrong errors: %q", errOut.String())
+	}
+	if !strings.HasPrefix(helpOut.String(), "Usage:\n    greet") {
+		t.Fatal("help not written:", helpOut.String())
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	p := NewCLI()
+	p.NewCommand("root", &shellTestRoot{})
+	out := &bytes.Buffer{}
+	ed := &lineEditor{
+		out:    out,
+		prompt: "> ",
+		complete: func(line string) []string {
+			words, _ := splitCompletionLine("root " + line)
+			return newParser(p).Complete(words)
+		},
+	}
+	read := func(in string) (string, error) {
+		ed.in = bufio.NewReader(strings.NewReader(in))
+		return ed.ReadLine()
+	}
+
+	cases := []struct {
+		in     string
+		expect string
+	}{
+		{"helo\x1b[Dl\r", "hello"},
+		{"abc\x01x\x05y\r", "xabcy"},
+		{"abc\x7f\x7fd\r", "ad"},
+		{"one two\x15three\r", "three"},
+		{"\x1b[A\x1b[A!\r", "ad!"},
+		{"\x10\x0e\r", ""},
+		{"gr\t--n\tbob\r", "greet --name bob"},
+		{"st\t\r", "status "},
+		{"s\t\r", "s"},
+	}
+	for _, c := range cases {
+		line, err := read(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != c.expect {
+			t.Fatalf("wrong line for %q: %q", c.in, line)
+		}
+	}
+	if strings.Join(ed.history, ",") != "hello,xabcy,ad,three,ad!,greet --name bob,status ,s" {
+		t.Fatal("wrong history:", ed.history)
+	}
+
+	if !strings.Contains(out.String(), "\r\nshell  status\r\n") {
+		t.Fatal("candidates not listed")
+	}
+
+	if _, err := read("\x04"); err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+	if _, err := read("abc\x03"); err != errInterrupted {
+		t.Fatal("expected interrupt, got:", err)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrSignal is returned by Run when the execution was interrupted by a signal.
+// Err is the error returned by the command chain, if any
+type ErrSignal struct {
+	Signal os.Signal
+	Err    error
+}
+
+func (e ErrSignal) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("interrupted by signal: %s", e.Signal)
+	}
+	return fmt.Sprintf("interrupted by signal: %s: %v", e.Signal, e.Err)
+}
+
+func (e ErrSignal) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns 128 plus the signal number, 130 for SIGINT & 143 for SIGTERM
+func (e ErrSignal) ExitCode() int {
+	if s, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return ExitCanceled
+}
+
+type signalKey struct{}
+
+type signalState struct {
+	mu  sync.Mutex
+	sig os.Signal
+}
+
+func (s *signalState) set(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sig = sig
+}
+
+func (s *signalState) get() os.Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sig
+}
+
+// SignalFromContext returns the signal that canceled the context passed to the
+// runners or nil if no signal was received
+func SignalFromContext(ctx context.Context) os.Signal {
+	if st, ok := ctx.Value(signalKey{}).(*signalState); ok {
+		return st.get()
+	}
+	return nil
+}
+
+// handleSignals cancels the returned context on the first signal and exits on
+// the second. stop must be called to release the signal handler
+func (cli *CLI) handleSignals(ctx context.Context) (_ context.Context, stop func()) {
+	st := &signalState{}
+	ctx, cancel := context.WithCancel(ctx)
+	ctx = context.WithValue(ctx, signalKey{}, st)
+
+	ch := make(chan os.Signal, 2)
+	cli.signalNotify(ch, cli.options.signals...)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-ch:
+			st.set(sig)
+			cancel()
+		case <-done:
+			return
+		}
+		// second signal forces exit
+		select {
+		case sig := <-ch:
+			cli.osExit(ErrSignal{Signal: sig}.ExitCode())
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(ch)
+		close(done)
+		cancel()
+	}
+}
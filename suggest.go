@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+)
+
+// editDistance returns the Damerau-Levenshtein distance (optimal string
+// alignment) between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(v int, vals ...int) int {
+	for _, i := range vals {
+		if i < v {
+			v = i
+		}
+	}
+	return v
+}
+
+// suggest returns the candidates within maxDist of s or having s as prefix,
+// closest first
+func suggest(s string, candidates []string, maxDist uint) []string {
+	if maxDist == 0 || s == "" {
+		return nil
+	}
+	type match struct {
+		name string
+		dist int
+	}
+	matches := []match{}
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		dist := editDistance(strings.ToLower(s), strings.ToLower(c))
+		if dist <= int(maxDist) || (len(s) > 3 && strings.HasPrefix(c, s)) {
+			matches = append(matches, match{c, dist})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].dist < matches[j].dist
+	})
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.name)
+	}
+	return out
+}
@@ -7,38 +7,77 @@ import (
 
 // StructTags struct tags values
 type StructTags struct {
-	Cli      string
-	Cmd      string
-	Long     string
-	Short    string
-	Env      string
-	Default  string
-	Usage    string
-	Complete string
+	Cli        string
+	Cmd        string
+	Long       string
+	Short      string
+	Env        string
+	Default    string
+	Usage      string
+	Complete   string
+	Min        string
+	Max        string
+	MinLen     string
+	MaxLen     string
+	Pattern    string
+	OneOf      string
+	RequiredIf string
+	Excludes   string
+	Inject     string
+	Alias      string
+	Prompt     string
 }
 
 func (st StructTags) parseTags(t reflect.StructTag) structTags {
 	return structTags{
-		Cli:      parseCliTag(t.Get(st.Cli)),
-		Cmd:      t.Get(st.Cmd),
-		Long:     parseLongTag(t.Get(st.Long)),
-		Short:    t.Get(st.Short),
-		Env:      parseEnvTag(t.Get(st.Env)),
-		Default:  t.Get(st.Default),
-		Usage:    t.Get(st.Usage),
-		Complete: t.Get(st.Complete),
+		Cli:        parseCliTag(t.Get(st.Cli)),
+		Cmd:        t.Get(st.Cmd),
+		Long:       parseLongTag(t.Get(st.Long)),
+		Short:      t.Get(st.Short),
+		Env:        parseEnvTag(t.Get(st.Env)),
+		Default:    t.Get(st.Default),
+		Usage:      t.Get(st.Usage),
+		Complete:   t.Get(st.Complete),
+		Min:        t.Get(st.Min),
+		Max:        t.Get(st.Max),
+		MinLen:     t.Get(st.MinLen),
+		MaxLen:     t.Get(st.MaxLen),
+		Pattern:    t.Get(st.Pattern),
+		OneOf:      t.Get(st.OneOf),
+		RequiredIf: t.Get(st.RequiredIf),
+		Excludes:   t.Get(st.Excludes),
+		Inject:     lookupTag(t, st.Inject),
+		Alias:      t.Get(st.Alias),
+		Prompt:     t.Get(st.Prompt),
 	}
 }
 
 type structTags struct {
-	Cli      *cliTag
-	Cmd      string
-	Long     *longTag
-	Short    string
-	Env      *envTag
-	Default  string
-	Usage    string
-	Complete string
+	Cli        *cliTag
+	Cmd        string
+	Long       *longTag
+	Short      string
+	Env        *envTag
+	Default    string
+	Usage      string
+	Complete   string
+	Min        string
+	Max        string
+	MinLen     string
+	MaxLen     string
+	Pattern    string
+	OneOf      string
+	RequiredIf string
+	Excludes   string
+	Inject     bool
+	Alias      string
+	Prompt     string
+}
+
+// lookupTag reports whether the tag key is present, even if empty
+func lookupTag(t reflect.StructTag, key string) bool {
+	_, ok := t.Lookup(key)
+	return ok
 }
 
 func (st structTags) IsIgnored() bool {
@@ -71,12 +110,24 @@ type cliTag struct {
 	required   bool
 	positional bool
 	global     bool
+	parent     bool
+	secret     bool
+	groups     []groupTag
+}
+
+type groupTag struct {
+	kind groupKind
+	name string
 }
 
 func parseCliTag(s string) *cliTag {
 	tag := &cliTag{}
 	parts := strings.Split(s, ",")
-	for _, key := range parts {
+	for _, part := range parts {
+		key, val := part, ""
+		if i := strings.Index(part, "="); i != -1 {
+			key, val = part[:i], part[i+1:]
+		}
 		switch strings.ToLower(key) {
 		case "required":
 			tag.required = true
@@ -84,6 +135,16 @@ func parseCliTag(s string) *cliTag {
 			tag.positional = true
 		case "global":
 			tag.global = true
+		case "parent":
+			tag.parent = true
+		case "secret":
+			tag.secret = true
+		case "xor":
+			tag.groups = append(tag.groups, groupTag{groupXor, val})
+		case "and":
+			tag.groups = append(tag.groups, groupTag{groupAnd, val})
+		case "onerequired":
+			tag.groups = append(tag.groups, groupTag{groupOneRequired, val})
 		}
 	}
 	return tag
@@ -116,10 +177,14 @@ func parseLongTag(s string) *longTag {
 
 type envTag struct {
 	name     string
+	aliases  []envVar
 	explicit bool
 	ignored  bool
 }
 
+// envDeprecated marks a name of the env tag as deprecated
+const envDeprecated = ":deprecated"
+
 func parseEnvTag(s string) *envTag {
 	tag := &envTag{}
 	if s == "-" {
@@ -127,12 +192,20 @@ func parseEnvTag(s string) *envTag {
 		return tag
 	}
 	parts := strings.Split(s, ",")
-	switch len(parts) {
-	case 1:
-		tag.name = parts[0]
-	case 2:
-		tag.name = parts[0]
-		if strings.ToLower(parts[1]) == "explicit" {
+	names := strings.Split(parts[0], "|")
+	tag.name = names[0]
+	if strings.HasSuffix(tag.name, envDeprecated) {
+		panic("primary env var name cannot be deprecated: " + tag.name)
+	}
+	for _, n := range names[1:] {
+		tag.aliases = append(tag.aliases, envVar{
+			name:       strings.TrimSuffix(n, envDeprecated),
+			deprecated: strings.HasSuffix(n, envDeprecated),
+		})
+	}
+	for _, opt := range parts[1:] {
+		switch strings.ToLower(opt) {
+		case "explicit":
 			tag.explicit = true
 		}
 	}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package cli
+
+import "errors"
+
+// isTerminal reports whether fd is a terminal. Terminals are only supported
+// on linux
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode not supported")
+}
+
+func disableEcho(fd uintptr) (func(), error) {
+	return nil, errors.New("echo control not supported")
+}
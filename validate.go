@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator is implemented by commands and argument structs to validate
+// the parsed values. It is called after flags, env & defaults are set
+type Validator interface {
+	Validate() error
+}
+
+// ErrValidation is returned when the Validator of a command or of one of its
+// argument structs fails
+type ErrValidation struct {
+	Command string
+	Err     error
+}
+
+func (e ErrValidation) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrValidation) Unwrap() error {
+	return e.Err
+}
+
+func (e ErrValidation) ExitCode() int {
+	return ExitUsage
+}
+
+// Validate calls the validators of the argument structs and then the
+// validator of the command itself
+func (c *command) Validate() (errs []error) {
+	paths := make([]*path, 0, len(c.argStructs)+1)
+	paths = append(paths, c.argStructs...)
+	for _, p := range append(paths, c.path) {
+		v := p.valueDeref()
+		if v.CanAddr() {
+			v = v.Addr()
+		}
+		vld, ok := v.Interface().(Validator)
+		if !ok {
+			continue
+		}
+		if err := vld.Validate(); err != nil {
+			errs = append(errs, ErrValidation{Command: c.Name, Err: err})
+		}
+	}
+	return
+}
+
+// setValidation parses the validation tags. Panics on invalid tag values
+func (a *argument) setValidation(tags structTags) {
+	t := a.typ
+	if isPtr(t) {
+		t = t.Elem()
+	}
+	if a.isSlice {
+		t = t.Elem()
+	}
+	parseFloat := func(tag, s string) *float64 {
+		if s == "" {
+			return nil
+		}
+		if !isNumber(t) || a.enum != nil {
+			panic(fmt.Sprintf("%s tag on non number: %s", tag, a.Name()))
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid %s tag for %s: %s", tag, a.Name(), s))
+		}
+		return &f
+	}
+	parseLen := func(tag, s string) int {
+		if s == "" {
+			return -1
+		}
+		if !(isString(t) || a.isSlice) {
+			panic(fmt.Sprintf("%s tag on non string or slice: %s", tag, a.Name()))
+		}
+		l, err := strconv.Atoi(s)
+		if err != nil || l < 0 {
+			panic(fmt.Sprintf("invalid %s tag for %s: %s", tag, a.Name(), s))
+		}
+		return l
+	}
+	a.min = parseFloat("min", tags.Min)
+	a.max = parseFloat("max", tags.Max)
+	a.minLen = parseLen("minlen", tags.MinLen)
+	a.maxLen = parseLen("maxlen", tags.MaxLen)
+	if tags.Pattern != "" {
+		if !isString(t) {
+			panic("pattern tag on non string: " + a.Name())
+		}
+		re, err := regexp.Compile(tags.Pattern)
+		if err != nil {
+			panic(fmt.Sprintf("invalid pattern tag for %s: %v", a.Name(), err))
+		}
+		a.pattern = re
+	}
+	if tags.OneOf != "" {
+		a.oneOf = strings.Split(tags.OneOf, ",")
+	}
+}
+
+// Validate checks the value against the validation tags
+func (a *argument) Validate() error {
+	if !a.IsSet() {
+		return nil
+	}
+	v := a.path.valueDeref()
+	if a.isSlice {
+		if a.minLen != -1 && v.Len() < a.minLen {
+			return a.validationError(v, fmt.Sprintf("must have at least %d values", a.minLen))
+		}
+		if a.maxLen != -1 && v.Len() > a.maxLen {
+			return a.validationError(v, fmt.Sprintf("must have at most %d values", a.maxLen))
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := a.validateScalar(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := a.validateScalar(v); err != nil {
+		return err
+	}
+	if isString(v.Type()) {
+		l := utf8.RuneCountInString(v.String())
+		if a.minLen != -1 && l < a.minLen {
+			return a.validationError(v, fmt.Sprintf("must be at least %d characters", a.minLen))
+		}
+		if a.maxLen != -1 && l > a.maxLen {
+			return a.validationError(v, fmt.Sprintf("must be at most %d characters", a.maxLen))
+		}
+	}
+	return nil
+}
+
+func (a *argument) validateScalar(v reflect.Value) error {
+	if a.min != nil || a.max != nil {
+		var f float64
+		switch {
+		case isInt(v.Type()):
+			f = float64(v.Int())
+		case isUint(v.Type()):
+			f = float64(v.Uint())
+		case isFloat(v.Type()):
+			f = v.Float()
+		}
+		if a.min != nil && f < *a.min {
+			return a.validationError(v, "must be at least "+strconv.FormatFloat(*a.min, 'f', -1, 64))
+		}
+		if a.max != nil && f > *a.max {
+			return a.validationError(v, "must be at most "+strconv.FormatFloat(*a.max, 'f', -1, 64))
+		}
+	}
+	if a.pattern != nil && !a.pattern.MatchString(v.String()) {
+		return a.validationError(v, "must match "+a.pattern.String())
+	}
+	if len(a.oneOf) > 0 {
+		s := a.valueString(v)
+		for _, o := range a.oneOf {
+			// enum names are case insensitive
+			if o == s || (a.enum != nil && strings.EqualFold(o, s)) {
+				return nil
+			}
+		}
+		return a.validationError(v, "must be one of: "+strings.Join(a.oneOf, ", "))
+	}
+	return nil
+}
+
+// valueString returns v as a string. Enums are returned by name
+func (a *argument) valueString(v reflect.Value) string {
+	if a.enum != nil {
+		return a.enum.Name(v.Interface())
+	}
+	if isArray(v.Type()) {
+		out := []string{}
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, a.valueString(v.Index(i)))
+		}
+		return strings.Join(out, " ")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (a *argument) validationError(v reflect.Value, reason string) error {
+	return a.invalidValue(a.valueString(v), errors.New(reason))
+}